@@ -0,0 +1,268 @@
+package render
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"strconv"
+)
+
+// binaryEncoder writes a valueNode in a binary format. Numbers are encoded
+// as integers if they are integral and fit into 64 bits, and as float64
+// otherwise.
+type binaryEncoder interface {
+	null()
+	boolean(b bool)
+	int(i int64)
+	uint(u uint64)
+	float(f float64)
+	str(s string)
+	array(n int)
+	object(n int)
+}
+
+func encodeBinary(e binaryEncoder, n *valueNode) {
+	switch n.kind {
+	case '{':
+		e.object(len(n.values))
+		for i, value := range n.values {
+			e.str(n.keys[i])
+			encodeBinary(e, value)
+		}
+		return
+	case '[':
+		e.array(len(n.values))
+		for _, value := range n.values {
+			encodeBinary(e, value)
+		}
+		return
+	}
+
+	switch s := n.scalar.(type) {
+	case nil:
+		e.null()
+	case bool:
+		e.boolean(s)
+	case string:
+		e.str(s)
+	case json.Number:
+		if i, err := strconv.ParseInt(s.String(), 10, 64); err == nil {
+			if i >= 0 {
+				e.uint(uint64(i))
+			} else {
+				e.int(i)
+			}
+		} else if u, err := strconv.ParseUint(s.String(), 10, 64); err == nil {
+			e.uint(u)
+		} else {
+			f, _ := s.Float64()
+			e.float(f)
+		}
+	}
+}
+
+// encodeMsgPack encodes v as MessagePack, the way JSON encodes it.
+func encodeMsgPack(v interface{}) ([]byte, error) {
+	n, err := decodeValue(v)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &msgPackEncoder{}
+	encodeBinary(e, n)
+	return e.buf.Bytes(), nil
+}
+
+type msgPackEncoder struct {
+	buf bytes.Buffer
+}
+
+func (e *msgPackEncoder) null() {
+	e.buf.WriteByte(0xc0)
+}
+
+func (e *msgPackEncoder) boolean(b bool) {
+	if b {
+		e.buf.WriteByte(0xc3)
+	} else {
+		e.buf.WriteByte(0xc2)
+	}
+}
+
+func (e *msgPackEncoder) int(i int64) {
+	switch {
+	case i >= -32:
+		e.buf.WriteByte(byte(i))
+	case i >= math.MinInt8:
+		e.buf.Write([]byte{0xd0, byte(i)})
+	case i >= math.MinInt16:
+		e.buf.WriteByte(0xd1)
+		e.uint16(uint16(i))
+	case i >= math.MinInt32:
+		e.buf.WriteByte(0xd2)
+		e.uint32(uint32(i))
+	default:
+		e.buf.WriteByte(0xd3)
+		e.uint64(uint64(i))
+	}
+}
+
+func (e *msgPackEncoder) uint(u uint64) {
+	switch {
+	case u <= math.MaxInt8:
+		e.buf.WriteByte(byte(u))
+	case u <= math.MaxUint8:
+		e.buf.Write([]byte{0xcc, byte(u)})
+	case u <= math.MaxUint16:
+		e.buf.WriteByte(0xcd)
+		e.uint16(uint16(u))
+	case u <= math.MaxUint32:
+		e.buf.WriteByte(0xce)
+		e.uint32(uint32(u))
+	default:
+		e.buf.WriteByte(0xcf)
+		e.uint64(u)
+	}
+}
+
+func (e *msgPackEncoder) float(f float64) {
+	e.buf.WriteByte(0xcb)
+	e.uint64(math.Float64bits(f))
+}
+
+func (e *msgPackEncoder) str(s string) {
+	e.length(len(s), 0xa0, 32, 0xd9, 0xda, 0xdb)
+	e.buf.WriteString(s)
+}
+
+func (e *msgPackEncoder) array(n int) {
+	e.length(n, 0x90, 16, 0, 0xdc, 0xdd)
+}
+
+func (e *msgPackEncoder) object(n int) {
+	e.length(n, 0x80, 16, 0, 0xde, 0xdf)
+}
+
+// length writes the header of a string, array or map of length n. A zero
+// code8 means the type has no 8-bit length form.
+func (e *msgPackEncoder) length(n int, fix byte, fixMax int, code8, code16, code32 byte) {
+	switch {
+	case n < fixMax:
+		e.buf.WriteByte(fix | byte(n))
+	case code8 != 0 && n <= math.MaxUint8:
+		e.buf.Write([]byte{code8, byte(n)})
+	case n <= math.MaxUint16:
+		e.buf.WriteByte(code16)
+		e.uint16(uint16(n))
+	default:
+		e.buf.WriteByte(code32)
+		e.uint32(uint32(n))
+	}
+}
+
+func (e *msgPackEncoder) uint16(u uint16) {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], u)
+	e.buf.Write(b[:])
+}
+
+func (e *msgPackEncoder) uint32(u uint32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], u)
+	e.buf.Write(b[:])
+}
+
+func (e *msgPackEncoder) uint64(u uint64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], u)
+	e.buf.Write(b[:])
+}
+
+// encodeCBOR encodes v as CBOR, the way JSON encodes it.
+func encodeCBOR(v interface{}) ([]byte, error) {
+	n, err := decodeValue(v)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &cborEncoder{}
+	encodeBinary(e, n)
+	return e.buf.Bytes(), nil
+}
+
+// CBOR major types, see RFC 8949.
+const (
+	cborUint   = 0 << 5
+	cborNegInt = 1 << 5
+	cborText   = 3 << 5
+	cborArray  = 4 << 5
+	cborMap    = 5 << 5
+)
+
+type cborEncoder struct {
+	buf bytes.Buffer
+}
+
+func (e *cborEncoder) null() {
+	e.buf.WriteByte(0xf6)
+}
+
+func (e *cborEncoder) boolean(b bool) {
+	if b {
+		e.buf.WriteByte(0xf5)
+	} else {
+		e.buf.WriteByte(0xf4)
+	}
+}
+
+func (e *cborEncoder) int(i int64) {
+	e.head(cborNegInt, uint64(-1-i))
+}
+
+func (e *cborEncoder) uint(u uint64) {
+	e.head(cborUint, u)
+}
+
+func (e *cborEncoder) float(f float64) {
+	var b [9]byte
+	b[0] = 0xfb
+	binary.BigEndian.PutUint64(b[1:], math.Float64bits(f))
+	e.buf.Write(b[:])
+}
+
+func (e *cborEncoder) str(s string) {
+	e.head(cborText, uint64(len(s)))
+	e.buf.WriteString(s)
+}
+
+func (e *cborEncoder) array(n int) {
+	e.head(cborArray, uint64(n))
+}
+
+func (e *cborEncoder) object(n int) {
+	e.head(cborMap, uint64(n))
+}
+
+// head writes the initial bytes of a data item with its argument.
+func (e *cborEncoder) head(major byte, arg uint64) {
+	var b [9]byte
+	switch {
+	case arg < 24:
+		e.buf.WriteByte(major | byte(arg))
+	case arg <= math.MaxUint8:
+		e.buf.Write([]byte{major | 24, byte(arg)})
+	case arg <= math.MaxUint16:
+		b[0] = major | 25
+		binary.BigEndian.PutUint16(b[1:], uint16(arg))
+		e.buf.Write(b[:3])
+	case arg <= math.MaxUint32:
+		b[0] = major | 26
+		binary.BigEndian.PutUint32(b[1:], uint32(arg))
+		e.buf.Write(b[:5])
+	default:
+		b[0] = major | 27
+		binary.BigEndian.PutUint64(b[1:], arg)
+		e.buf.Write(b[:])
+	}
+}
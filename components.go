@@ -0,0 +1,313 @@
+package render
+
+import (
+	"fmt"
+	"html/template"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template/parse"
+)
+
+// componentDirectory is the directory below the templates that components
+// are loaded from.
+const componentDirectory = "components/"
+
+// componentSpec lists the props and slots a component declares with
+// {{ props "name" ... }} and {{ slots "name" ... }}. Names ending in "?" are
+// optional.
+type componentSpec struct {
+	// declared is false if the component declares neither, in which case
+	// its calls are not checked.
+	declared bool
+	props    []componentParam
+	slots    []componentParam
+}
+
+type componentParam struct {
+	name     string
+	optional bool
+}
+
+// componentSpecs returns the components found in the templates, by name.
+// There are none if Options.Funcs has a component func of its own, and
+// props or slots funcs of its own are not read as declarations.
+func componentSpecs(opt *Options, templates *template.Template, files map[string]templateFile) map[string]*componentSpec {
+	specs := map[string]*componentSpec{}
+	if !builtinFunc(opt, "component") {
+		return specs
+	}
+	for _, file := range files {
+		if !strings.HasPrefix(file.name, componentDirectory) {
+			continue
+		}
+
+		t := templates.Lookup(file.name)
+		spec := &componentSpec{}
+		if args, ok := declaration(t, "props"); ok && builtinFunc(opt, "props") {
+			spec.declared = true
+			spec.props = componentParams(args)
+		}
+		if args, ok := declaration(t, "slots"); ok && builtinFunc(opt, "slots") {
+			spec.declared = true
+			spec.slots = componentParams(args)
+		}
+		specs[strings.TrimPrefix(file.name, componentDirectory)] = spec
+	}
+	return specs
+}
+
+func componentParams(names []string) []componentParam {
+	params := make([]componentParam, len(names))
+	for i, name := range names {
+		params[i] = componentParam{
+			name:     strings.TrimSuffix(name, "?"),
+			optional: strings.HasSuffix(name, "?"),
+		}
+	}
+	return params
+}
+
+// check reports the first unknown or missing prop or slot of a call that
+// passes the given names.
+func (s *componentSpec) check(names []string) error {
+	if !s.declared {
+		return nil
+	}
+
+	sorted := append([]string(nil), names...)
+	sort.Strings(sorted)
+	for _, name := range sorted {
+		if findParam(s.props, name) < 0 && findParam(s.slots, name) < 0 {
+			return fmt.Errorf("unknown prop %q", name)
+		}
+	}
+	for _, p := range s.props {
+		if !p.optional && indexOf(names, p.name) < 0 {
+			return fmt.Errorf("missing prop %q", p.name)
+		}
+	}
+	for _, p := range s.slots {
+		if !p.optional && indexOf(names, p.name) < 0 {
+			return fmt.Errorf("missing slot %q", p.name)
+		}
+	}
+	return nil
+}
+
+func findParam(params []componentParam, name string) int {
+	for i, p := range params {
+		if p.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// component renders a component with the given props, passed as pairs of
+// names and values or as a single map built with dict. Slots are props that
+// hold HTML, e.g. from partialWith; strings passed to them are escaped.
+func (c *templateClone) component(name string, args ...interface{}) (template.HTML, error) {
+	spec, ok := c.set.components[name]
+	if !ok {
+		return "", fmt.Errorf("component %q is not defined", name)
+	}
+
+	var props map[string]interface{}
+	if m, ok := singleMap(args); ok {
+		props = make(map[string]interface{}, len(m))
+		for k, v := range m {
+			props[k] = v
+		}
+	} else {
+		var err error
+		if props, err = dict(args...); err != nil {
+			return "", fmt.Errorf("component %q: %v", name, err)
+		}
+	}
+
+	names := make([]string, 0, len(props))
+	for k := range props {
+		names = append(names, k)
+	}
+	if err := spec.check(names); err != nil {
+		return "", fmt.Errorf("component %q: %v", name, err)
+	}
+
+	// Optional props and slots are always set, so that they can be tested.
+	for _, p := range append(spec.props, spec.slots...) {
+		if _, ok := props[p.name]; !ok {
+			props[p.name] = nil
+		}
+	}
+	for _, p := range spec.slots {
+		switch v := props[p.name].(type) {
+		case nil, template.HTML:
+		case string:
+			props[p.name] = template.HTML(template.HTMLEscapeString(v))
+		default:
+			return "", fmt.Errorf("component %q: slot %q expects HTML, got %T", name, p.name, v)
+		}
+	}
+
+	if err := c.contextErr(); err != nil {
+		return "", err
+	}
+
+	buf, err := c.execute(componentDirectory+name, props)
+	// Return safe HTML here since we are rendering our own template.
+	return template.HTML(buf.String()), err
+}
+
+func singleMap(args []interface{}) (map[string]interface{}, bool) {
+	if len(args) != 1 {
+		return nil, false
+	}
+	m, ok := args[0].(map[string]interface{})
+	return m, ok
+}
+
+// checkComponents adds an error to errs for every component call with a
+// constant name that refers to a missing component, or passes constant prop
+// names that the component does not accept.
+func checkComponents(opt *Options, templates *template.Template, files map[string]templateFile, errs *CompileError) {
+	if !builtinFunc(opt, "component") {
+		return
+	}
+	specs := componentSpecs(opt, templates, files)
+	paths := map[string]string{}
+	for path, file := range files {
+		paths[file.name] = path
+	}
+
+	var all []*template.Template
+	for _, t := range templates.Templates() {
+		if t.Tree != nil && t.Tree.Root != nil {
+			all = append(all, t)
+		}
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].Name() < all[j].Name()
+	})
+
+	// Sections share their trees with the templates they were defined as.
+	seen := map[*parse.Tree]bool{}
+	for _, t := range all {
+		if seen[t.Tree] {
+			continue
+		}
+		seen[t.Tree] = true
+
+		path, ok := paths[t.Tree.ParseName]
+		if !ok {
+			continue
+		}
+		walkCommands(t.Tree.Root, func(cmd *parse.CommandNode) {
+			name, names, ok := componentCall(cmd)
+			if !ok {
+				return
+			}
+
+			var err error
+			if spec, ok := specs[name]; !ok {
+				err = fmt.Errorf("component %q is not defined", name)
+			} else if names != nil {
+				err = spec.check(names)
+			}
+			if err != nil {
+				errs.add(path, fmt.Errorf("template: %s:%d: component %q called from %q: %v", t.Tree.ParseName, commandLine(t.Tree, cmd), name, t.Name(), err))
+			}
+		})
+	}
+}
+
+// componentCall returns the component name of a call with a constant name,
+// along with the prop names if they are constant as well.
+func componentCall(cmd *parse.CommandNode) (string, []string, bool) {
+	if len(cmd.Args) < 2 {
+		return "", nil, false
+	}
+	if ident, ok := cmd.Args[0].(*parse.IdentifierNode); !ok || ident.Ident != "component" {
+		return "", nil, false
+	}
+	name, ok := cmd.Args[1].(*parse.StringNode)
+	if !ok {
+		return "", nil, false
+	}
+
+	args := cmd.Args[2:]
+	if len(args) == 1 {
+		// A single (dict ...) argument.
+		pipe, ok := args[0].(*parse.PipeNode)
+		if !ok || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) == 0 {
+			return name.Text, nil, true
+		}
+		if ident, ok := pipe.Cmds[0].Args[0].(*parse.IdentifierNode); !ok || ident.Ident != "dict" {
+			return name.Text, nil, true
+		}
+		args = pipe.Cmds[0].Args[1:]
+	}
+	if len(args)%2 != 0 {
+		return name.Text, nil, true
+	}
+
+	names := make([]string, 0, len(args)/2)
+	for i := 0; i < len(args); i += 2 {
+		s, ok := args[i].(*parse.StringNode)
+		if !ok {
+			return name.Text, nil, true
+		}
+		names = append(names, s.Text)
+	}
+	return name.Text, names, true
+}
+
+// commandLine returns the line a command is on.
+func commandLine(tree *parse.Tree, cmd *parse.CommandNode) int {
+	location, _ := tree.ErrorContext(cmd)
+	parts := strings.Split(location, ":")
+	if len(parts) < 3 {
+		return 0
+	}
+	line, _ := strconv.Atoi(parts[len(parts)-2])
+	return line
+}
+
+// walkCommands calls fn for every command in the tree below node.
+func walkCommands(node parse.Node, fn func(*parse.CommandNode)) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			walkCommands(child, fn)
+		}
+	case *parse.ActionNode:
+		walkCommands(n.Pipe, fn)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			fn(cmd)
+			for _, arg := range cmd.Args {
+				walkCommands(arg, fn)
+			}
+		}
+	case *parse.IfNode:
+		walkBranch(&n.BranchNode, fn)
+	case *parse.RangeNode:
+		walkBranch(&n.BranchNode, fn)
+	case *parse.WithNode:
+		walkBranch(&n.BranchNode, fn)
+	case *parse.TemplateNode:
+		walkCommands(n.Pipe, fn)
+	}
+}
+
+func walkBranch(b *parse.BranchNode, fn func(*parse.CommandNode)) {
+	walkCommands(b.Pipe, fn)
+	walkCommands(b.List, fn)
+	walkCommands(b.ElseList, fn)
+}
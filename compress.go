@@ -0,0 +1,287 @@
+package render
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// ContentEncoding header constant.
+	ContentEncoding = "Content-Encoding"
+
+	// defaultCompressionMinSize is the size below which responses are not compressed.
+	defaultCompressionMinSize = 1024
+)
+
+// defaultCompressionTypes are the content types compressed by default.
+var defaultCompressionTypes = []string{
+	ContentHTML,
+	ContentText,
+	ContentXML,
+	ContentXHTML,
+	ContentJSON,
+	ContentJSONP,
+	ContentProblemJSON,
+	ContentProblemXML,
+	ContentYAML,
+	ContentNDJSON,
+	ContentCSV,
+	ContentTSV,
+	"application/xml",
+	"image/svg+xml",
+	"text/css",
+	"text/javascript",
+}
+
+// compressor is implemented by gzip.Writer and zlib.Writer.
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+var compressors = map[string]*sync.Pool{
+	"gzip": {
+		New: func() interface{} { return gzip.NewWriter(nil) },
+	},
+	"deflate": {
+		New: func() interface{} { return zlib.NewWriter(nil) },
+	},
+}
+
+// compressWriter compresses a response with the given encoding. Writes are
+// buffered until the response is flushed or closed, so that small responses
+// and responses of other content types can still be sent as they are, and
+// the Content-Length of a fully buffered response is known. In streaming
+// mode compression already starts once the minimum size was buffered.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	types    []string
+	minSize  int
+	stream   bool
+
+	status int
+	buf    bytes.Buffer
+	// started is set once the headers were sent. enc is only set if the
+	// response is compressed.
+	started bool
+	enc     compressor
+}
+
+// compress wraps w to compress the response to req, if compression is
+// enabled and accepted by the client. The returned func must be called once
+// rendering is done.
+func (r *Render) compress(w io.Writer, req *http.Request, stream bool) (io.Writer, func() error) {
+	hw, ok := w.(http.ResponseWriter)
+	if !ok || !r.opt.Compression {
+		return w, func() error { return nil }
+	}
+
+	addVary(hw.Header(), "Accept-Encoding")
+	encoding := negotiateEncoding(req.Header.Get("Accept-Encoding"))
+	if encoding == "" {
+		return w, func() error { return nil }
+	}
+
+	cw := &compressWriter{
+		ResponseWriter: hw,
+		encoding:       encoding,
+		types:          r.opt.CompressionTypes,
+		minSize:        r.opt.CompressionMinSize,
+		stream:         stream,
+	}
+	return cw, cw.close
+}
+
+// withCompression calls render with a compressing writer and closes it afterwards.
+func (r *Render) withCompression(w io.Writer, req *http.Request, stream bool, render func(w io.Writer) error) error {
+	cw, done := r.compress(w, req, stream)
+	err := render(cw)
+	if cerr := done(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (c *compressWriter) WriteHeader(status int) {
+	if c.status == 0 {
+		c.status = status
+	}
+}
+
+func (c *compressWriter) Write(b []byte) (int, error) {
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+
+	if c.started {
+		if c.enc != nil {
+			return c.enc.Write(b)
+		}
+		return c.ResponseWriter.Write(b)
+	}
+
+	n, _ := c.buf.Write(b)
+	if c.stream && c.buf.Len() >= c.minSize {
+		return n, c.start(c.compressible(c.status))
+	}
+	return n, nil
+}
+
+// Flush sends everything written so far, compressed if the content type
+// allows it, and flushes the underlying ResponseWriter.
+func (c *compressWriter) Flush() {
+	if !c.started {
+		if c.status == 0 {
+			c.status = http.StatusOK
+		}
+		if c.start(c.compressible(c.status)) != nil {
+			return
+		}
+	}
+	if c.enc != nil && c.enc.Flush() != nil {
+		return
+	}
+	if f, ok := c.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying ResponseWriter, for http.ResponseController.
+func (c *compressWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+// compressible reports whether the response may be compressed with the
+// given status.
+func (c *compressWriter) compressible(status int) bool {
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified {
+		return false
+	}
+
+	h := c.ResponseWriter.Header()
+	if h.Get(ContentEncoding) != "" || h.Get("Content-Range") != "" {
+		return false
+	}
+
+	contentType := mediaType(h.Get(ContentType))
+	for _, t := range c.types {
+		if strings.EqualFold(contentType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// willCompress reports whether a response with the given status and body
+// size is going to be compressed.
+func (c *compressWriter) willCompress(status, size int) bool {
+	return size >= c.minSize && c.compressible(status)
+}
+
+// start sends the headers and the buffered data, compressing from here on if
+// compress is set.
+func (c *compressWriter) start(compress bool) error {
+	c.started = true
+
+	if compress {
+		h := c.ResponseWriter.Header()
+		h.Del(ContentLength)
+		h.Set(ContentEncoding, c.encoding)
+		weakenETag(h)
+		c.enc = compressors[c.encoding].Get().(compressor)
+		c.enc.Reset(c.ResponseWriter)
+	}
+	c.ResponseWriter.WriteHeader(c.status)
+
+	if c.buf.Len() == 0 {
+		return nil
+	}
+	var err error
+	if c.enc != nil {
+		_, err = c.enc.Write(c.buf.Bytes())
+	} else {
+		_, err = c.ResponseWriter.Write(c.buf.Bytes())
+	}
+	c.buf.Reset()
+	return err
+}
+
+// close finishes the response. A response that is still fully buffered is
+// compressed at once, so its Content-Length can be set.
+func (c *compressWriter) close() error {
+	if c.started {
+		if c.enc == nil {
+			return nil
+		}
+		err := c.enc.Close()
+		compressors[c.encoding].Put(c.enc)
+		c.enc = nil
+		return err
+	}
+
+	// Nothing was rendered, e.g. because of an error.
+	if c.status == 0 {
+		return nil
+	}
+
+	if c.buf.Len() < c.minSize || !c.compressible(c.status) {
+		return c.start(false)
+	}
+
+	var out bytes.Buffer
+	enc := compressors[c.encoding].Get().(compressor)
+	defer compressors[c.encoding].Put(enc)
+	enc.Reset(&out)
+	enc.Write(c.buf.Bytes())
+	if err := enc.Close(); err != nil {
+		return err
+	}
+
+	c.started = true
+	h := c.ResponseWriter.Header()
+	h.Set(ContentEncoding, c.encoding)
+	h.Set(ContentLength, strconv.Itoa(out.Len()))
+	weakenETag(h)
+	c.ResponseWriter.WriteHeader(c.status)
+	_, err := out.WriteTo(c.ResponseWriter)
+	return err
+}
+
+// negotiateEncoding returns the supported content coding the client prefers
+// according to an Accept-Encoding header, or "" if it accepts none of them.
+// gzip is preferred over deflate when both are accepted equally.
+func negotiateEncoding(acceptEncoding string) string {
+	best := ""
+	bestQ := 0.0
+	wildcard := -1.0
+	explicit := map[string]float64{}
+
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, q := parseQuality(part)
+		switch coding = strings.ToLower(coding); coding {
+		case "gzip", "deflate":
+			explicit[coding] = q
+		case "*":
+			wildcard = q
+		}
+	}
+
+	for _, coding := range []string{"gzip", "deflate"} {
+		q, ok := explicit[coding]
+		if !ok {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = coding, q
+		}
+	}
+	return best
+}
@@ -0,0 +1,493 @@
+package render
+
+import (
+	"context"
+	"encoding"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ContentCSV header value for CSV data.
+	ContentCSV = "text/csv"
+	// ContentTSV header value for TSV data.
+	ContentTSV = "text/tab-separated-values"
+)
+
+// CSVOptions is a struct for overriding some rendering Options for a specific
+// CSV or TSV call.
+type CSVOptions struct {
+	// Columns to write, in order, by their header names. Defaults to all
+	// fields of a struct in declaration order, or all keys of the maps in
+	// sorted order.
+	Columns []string
+	// Filename makes the response an attachment with the given file name.
+	Filename string
+	// Headers and Cookies sent with the response, see Head.
+	Headers http.Header
+	Cookies []*http.Cookie
+}
+
+// CSV built-in renderer. It renders a slice or array, a channel, or an
+// Iterator of structs or of maps with string keys, one row per value, and
+// streams the rows to the client instead of buffering them.
+//
+// The header row is made from the field names of a struct, which can be
+// changed with a `csv` struct tag, or a `json` tag if there is none. Fields
+// tagged "-" are skipped and embedded structs are flattened. If the type of
+// the rows is not known upfront, as for an Iterator, it is taken from the
+// first row.
+//
+// An error after the header row was written is returned as a *StreamError
+// that counts the rows sent.
+type CSV struct {
+	Head
+	// Comma is the field delimiter. Defaults to ','.
+	Comma rune
+	// Columns to write, see CSVOptions.
+	Columns []string
+	// FlushEvery is the number of rows sent per flush. Defaults to 1000.
+	FlushEvery int
+}
+
+// defaultCSVFlushEvery is the default number of rows sent per flush.
+const defaultCSVFlushEvery = 1000
+
+// Render a CSV response.
+func (c CSV) Render(w io.Writer, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.IsValid() {
+		v = rv.Interface()
+	}
+
+	rows, err := newValueStream(v)
+	if err != nil {
+		return fmt.Errorf("render: CSV expects a slice, a channel or an Iterator of structs or maps, got %T", v)
+	}
+	table, err := newCSVTable(rows.v, c.Columns)
+	if err != nil {
+		return err
+	}
+
+	flushEvery := c.FlushEvery
+	if flushEvery <= 0 {
+		flushEvery = defaultCSVFlushEvery
+	}
+	flusher, _ := w.(http.Flusher)
+
+	cw := csv.NewWriter(w)
+	if c.Comma != 0 {
+		cw.Comma = c.Comma
+	}
+
+	started := false
+	start := func() error {
+		started = true
+		if hw, ok := w.(http.ResponseWriter); ok {
+			c.Head.Write(hw)
+		}
+		// Without columns there is nothing to write, not even blank lines.
+		if len(table.columns) == 0 {
+			return nil
+		}
+		return cw.Write(table.columns)
+	}
+	if table != nil {
+		if err := start(); err != nil {
+			return &StreamError{Err: err}
+		}
+	}
+
+	var record []string
+	written := 0
+	err = rows.each(context.Background(), func(v interface{}) error {
+		row := reflect.ValueOf(v)
+		if table == nil {
+			var err error
+			if table, err = newCSVTableForRow(row, c.Columns); err != nil {
+				return err
+			}
+			if err := start(); err != nil {
+				return err
+			}
+		}
+
+		if record == nil {
+			record = make([]string, len(table.columns))
+		}
+		if err := table.record(row, record); err != nil {
+			return err
+		}
+		if len(record) == 0 {
+			return nil
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+
+		written++
+		if written%flushEvery == 0 {
+			cw.Flush()
+			if err := cw.Error(); err != nil {
+				return err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		return nil
+	})
+
+	if err == nil && !started {
+		// There was no row to take the columns from.
+		if hw, ok := w.(http.ResponseWriter); ok {
+			c.Head.Write(hw)
+		}
+		return nil
+	}
+
+	cw.Flush()
+	if err == nil {
+		err = cw.Error()
+	}
+	if err != nil && started {
+		return &StreamError{Err: err, Written: written}
+	}
+	return err
+}
+
+// csvTable maps values to CSV records.
+type csvTable struct {
+	// elem is the type of the rows.
+	elem    reflect.Type
+	columns []string
+	// fields holds the index of the struct field of every column. It is nil
+	// for maps, whose columns are the keys.
+	fields [][]int
+}
+
+// newCSVTable returns the table for the rows of a slice, array or channel,
+// or nil if it can only be made once the first row was seen.
+func newCSVTable(rows reflect.Value, columns []string) (*csvTable, error) {
+	if rows.Kind() == reflect.Func {
+		return nil, nil
+	}
+
+	elem := rows.Type().Elem()
+	for elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	if elem.Kind() == reflect.Interface {
+		return nil, nil
+	}
+
+	t, err := csvTableOf(elem, columns)
+	if err != nil || t.fields != nil || len(t.columns) > 0 {
+		return t, err
+	}
+	if rows.Kind() == reflect.Chan {
+		// The columns of maps are taken from the first row.
+		return nil, nil
+	}
+	t.columns = csvMapKeys(rows)
+	return t, nil
+}
+
+// newCSVTableForRow returns the table for rows like the given one.
+func newCSVTableForRow(row reflect.Value, columns []string) (*csvTable, error) {
+	for row.Kind() == reflect.Ptr || row.Kind() == reflect.Interface {
+		if row.IsNil() {
+			return nil, fmt.Errorf("render: CSV cannot take the columns from a nil row")
+		}
+		row = row.Elem()
+	}
+	if !row.IsValid() {
+		return nil, fmt.Errorf("render: CSV cannot take the columns from a nil row")
+	}
+
+	t, err := csvTableOf(row.Type(), columns)
+	if err == nil && t.fields == nil && len(t.columns) == 0 {
+		t.columns = csvMapKeys(reflect.ValueOf([]interface{}{row.Interface()}))
+	}
+	return t, err
+}
+
+// csvTableOf returns the table for rows of the given type. The columns of
+// maps are left empty unless they are given.
+func csvTableOf(elem reflect.Type, columns []string) (*csvTable, error) {
+	t := &csvTable{elem: elem}
+	switch {
+	case elem.Kind() == reflect.Struct:
+		names, fields := csvFields(elem, nil)
+		if len(columns) == 0 {
+			t.columns, t.fields = names, fields
+			break
+		}
+
+		for _, column := range columns {
+			i := indexOf(names, column)
+			if i < 0 {
+				return nil, fmt.Errorf("render: unknown CSV column %q for %s", column, elem)
+			}
+			t.fields = append(t.fields, fields[i])
+		}
+		t.columns = columns
+	case elem.Kind() == reflect.Map && elem.Key().Kind() == reflect.String:
+		t.columns = columns
+	default:
+		return nil, fmt.Errorf("render: CSV expects structs or maps, got %s", elem)
+	}
+	return t, nil
+}
+
+// record fills in the values of a row. Nil rows have empty values.
+func (t *csvTable) record(row reflect.Value, record []string) error {
+	for (row.Kind() == reflect.Ptr || row.Kind() == reflect.Interface) && !row.IsNil() {
+		row = row.Elem()
+	}
+	if !row.IsValid() || row.Kind() == reflect.Ptr || row.Kind() == reflect.Interface {
+		for i := range record {
+			record[i] = ""
+		}
+		return nil
+	}
+	if row.Type() != t.elem {
+		return fmt.Errorf("render: CSV row of type %s does not match %s", row.Type(), t.elem)
+	}
+
+	for i, column := range t.columns {
+		var value reflect.Value
+		if t.fields != nil {
+			value = fieldByIndex(row, t.fields[i])
+		} else {
+			value = row.MapIndex(reflect.ValueOf(column).Convert(row.Type().Key()))
+		}
+		record[i] = csvValue(value)
+	}
+	return nil
+}
+
+// csvFields returns the header names and field indexes of the columns of a struct.
+func csvFields(t reflect.Type, index []int) ([]string, [][]int) {
+	var names []string
+	var fields [][]int
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		fieldIndex := append(append([]int(nil), index...), i)
+
+		tag := f.Tag.Get("csv")
+		if tag == "" {
+			tag = f.Tag.Get("json")
+		}
+		name := strings.Split(tag, ",")[0]
+		if name == "-" {
+			continue
+		}
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				n, fi := csvFields(ft, fieldIndex)
+				names = append(names, n...)
+				fields = append(fields, fi...)
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+		names = append(names, name)
+		fields = append(fields, fieldIndex)
+	}
+	return names, fields
+}
+
+// csvMapKeys returns the sorted keys of all maps in rows.
+func csvMapKeys(rows reflect.Value) []string {
+	seen := map[string]bool{}
+	var keys []string
+	for i := 0; i < rows.Len(); i++ {
+		row := rows.Index(i)
+		for (row.Kind() == reflect.Ptr || row.Kind() == reflect.Interface) && !row.IsNil() {
+			row = row.Elem()
+		}
+		if row.Kind() != reflect.Map {
+			continue
+		}
+
+		for _, key := range row.MapKeys() {
+			if k := key.String(); !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// fieldByIndex is like reflect.Value.FieldByIndex, but returns the zero
+// Value instead of panicking on nil embedded pointers.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 {
+			for v.Kind() == reflect.Ptr {
+				if v.IsNil() {
+					return reflect.Value{}
+				}
+				v = v.Elem()
+			}
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// csvValue formats a single value.
+func csvValue(v reflect.Value) string {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return ""
+	}
+
+	if v.CanInterface() {
+		switch i := v.Interface().(type) {
+		case encoding.TextMarshaler:
+			if b, err := i.MarshalText(); err == nil {
+				return string(b)
+			}
+		case fmt.Stringer:
+			return i.String()
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits())
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+func indexOf(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
+
+// contentDisposition returns a Content-Disposition header value of the given
+// type for a file name, see RFC 6266. Names that are not plain ASCII are sent
+// percent-encoded, along with an ASCII fallback for older clients.
+func contentDisposition(dispositionType, filename string) string {
+	if filename == "" {
+		return dispositionType
+	}
+
+	fallback := make([]byte, 0, len(filename))
+	plain := true
+	for _, r := range filename {
+		switch {
+		case r == '"' || r == '\\':
+			fallback = append(fallback, '\\', byte(r))
+		case r < ' ' || r > '~':
+			fallback = append(fallback, '_')
+			plain = false
+		default:
+			fallback = append(fallback, byte(r))
+		}
+	}
+
+	v := dispositionType + `; filename="` + string(fallback) + `"`
+	if !plain {
+		v += "; filename*=UTF-8''" + encodeExtValue(filename)
+	}
+	return v
+}
+
+// encodeExtValue percent-encodes s as an RFC 8187 ext-value.
+func encodeExtValue(s string) string {
+	const hex = "0123456789ABCDEF"
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') || strings.IndexByte("!#$&+-.^_`|~", c) >= 0 {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&0x0f])
+	}
+	return b.String()
+}
+
+// CSV writes out a slice of structs or maps as comma-separated values.
+func (r *Render) CSV(w io.Writer, status int, v interface{}, csvOpt ...CSVOptions) error {
+	return r.csv(w, status, v, r.opt.CSVContentType, ',', csvOpt)
+}
+
+// TSV writes out a slice of structs or maps as tab-separated values.
+func (r *Render) TSV(w io.Writer, status int, v interface{}, csvOpt ...CSVOptions) error {
+	return r.csv(w, status, v, r.opt.TSVContentType, '\t', csvOpt)
+}
+
+func (r *Render) csv(w io.Writer, status int, v interface{}, contentType string, comma rune, csvOpt []CSVOptions) error {
+	var opt CSVOptions
+	if len(csvOpt) > 0 {
+		opt = csvOpt[0]
+	}
+
+	head := Head{
+		ContentType: contentType + r.compiledCharset,
+		Status:      status,
+		Headers:     opt.Headers,
+		Cookies:     opt.Cookies,
+	}
+	if len(opt.Filename) > 0 {
+		head.Headers = http.Header{"Content-Disposition": {contentDisposition("attachment", opt.Filename)}}
+		for k, v := range opt.Headers {
+			head.Headers[k] = v
+		}
+	}
+
+	c := CSV{
+		Head:    head,
+		Comma:   comma,
+		Columns: opt.Columns,
+	}
+
+	return r.Render(w, c, v)
+}
@@ -0,0 +1,137 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"sync"
+)
+
+// deferredSwapScript moves the content of a deferred partial into its placeholder.
+const deferredSwapScript = `<script>(function(){var t=document.getElementById("%[1]s-content"),p=document.getElementById("%[1]s");if(t&&p){p.replaceWith(t.content);t.remove()}})()</script>`
+
+// deferredPartials are partials of a single render that are rendered
+// concurrently to the rest of the page and appended to the end of the
+// response as they complete.
+type deferredPartials struct {
+	mu      sync.Mutex
+	wg      sync.WaitGroup
+	next    int
+	pending int
+	ready   []deferredPartial
+	notify  chan struct{}
+}
+
+// deferredPartial is the result of a deferred partial.
+type deferredPartial struct {
+	id   string
+	html []byte
+	err  error
+}
+
+func newDeferredPartials() *deferredPartials {
+	return &deferredPartials{
+		notify: make(chan struct{}, 1),
+	}
+}
+
+// deferred renders a partial concurrently to the rest of the page. It
+// immediately returns a placeholder which is replaced by the partial once it
+// arrives at the end of the response.
+func (c *templateClone) deferred(partialName string) (template.HTML, error) {
+	if !c.state.layout {
+		return "", fmt.Errorf("deferred called with no layout defined")
+	}
+
+	fullPartialName, ok := c.lookupPartial(partialName, c.set.opt.RequirePartials)
+	if !ok {
+		return "", nil
+	}
+
+	id := c.state.deferred.start(func() ([]byte, error) {
+		buf, err := c.execute(fullPartialName, c.state.binding)
+		return buf.Bytes(), err
+	})
+
+	// Return safe HTML here since we are rendering our own placeholder.
+	return template.HTML(fmt.Sprintf(`<div id="%s"></div>`, id)), nil
+}
+
+// start runs render in the background and returns the id of its placeholder.
+func (d *deferredPartials) start(render func() ([]byte, error)) string {
+	d.mu.Lock()
+	id := fmt.Sprintf("render-deferred-%d", d.next)
+	d.next++
+	d.pending++
+	d.mu.Unlock()
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		html, err := render()
+
+		d.mu.Lock()
+		d.ready = append(d.ready, deferredPartial{id: id, html: html, err: err})
+		d.pending--
+		d.mu.Unlock()
+
+		select {
+		case d.notify <- struct{}{}:
+		default:
+		}
+	}()
+
+	return id
+}
+
+// writeTo waits for all deferred partials and writes them to w in the order
+// they complete, calling flush after each batch. It returns the first error
+// of any deferred partial.
+func (d *deferredPartials) writeTo(w io.Writer, flush func() error) error {
+	var firstErr error
+	for {
+		d.mu.Lock()
+		ready, pending := d.ready, d.pending
+		d.ready = nil
+		d.mu.Unlock()
+
+		for _, p := range ready {
+			if p.err != nil {
+				if firstErr == nil {
+					firstErr = p.err
+				}
+				continue
+			}
+			if err := p.writeTo(w); err != nil {
+				return err
+			}
+		}
+		if len(ready) > 0 && flush != nil {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+
+		if pending == 0 {
+			return firstErr
+		}
+		<-d.notify
+	}
+}
+
+func (p deferredPartial) writeTo(w io.Writer) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<template id="%s-content">`, p.id)
+	buf.Write(p.html)
+	buf.WriteString(`</template>`)
+	fmt.Fprintf(&buf, deferredSwapScript, p.id)
+
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// wait blocks until all deferred partials are done.
+func (d *deferredPartials) wait() {
+	d.wg.Wait()
+}
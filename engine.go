@@ -14,10 +14,14 @@ type Engine interface {
 	Render(io.Writer, interface{}) error
 }
 
-// Head defines the basic ContentType and Status fields.
+// Head defines the basic ContentType and Status fields, along with any
+// additional headers and cookies of the response.
 type Head struct {
 	ContentType string
 	Status      int
+	// Headers replace the values already set for the same keys.
+	Headers http.Header
+	Cookies []*http.Cookie
 }
 
 // Data built-in renderer.
@@ -30,8 +34,13 @@ type HTML struct {
 	Head
 	Name      string
 	Templates *template.Template
+	// Streaming writes the template to the response as it executes instead
+	// of buffering it first.
+	Streaming bool
 
-	bp GenericBufferPool
+	bp       GenericBufferPool
+	stream   *htmlStream
+	deferred *deferredPartials
 }
 
 // JSON built-in renderer.
@@ -62,12 +71,44 @@ type XML struct {
 	Prefix []byte
 }
 
+// MsgPack built-in renderer.
+type MsgPack struct {
+	Head
+}
+
+// CBOR built-in renderer.
+type CBOR struct {
+	Head
+}
+
+// YAML built-in renderer.
+type YAML struct {
+	Head
+	// Indent is the number of spaces nested blocks are indented by.
+	Indent int
+}
+
 // Write outputs the header content.
 func (h Head) Write(w http.ResponseWriter) {
+	h.writeHeaders(w)
 	w.Header().Set(ContentType, h.ContentType)
 	w.WriteHeader(h.Status)
 }
 
+// writeHeaders sets the headers and cookies of the Head.
+func (h Head) writeHeaders(w http.ResponseWriter) {
+	header := w.Header()
+	for k, v := range h.Headers {
+		header.Del(k)
+		for _, value := range v {
+			header.Add(k, value)
+		}
+	}
+	for _, cookie := range h.Cookies {
+		http.SetCookie(w, cookie)
+	}
+}
+
 // Render a data response.
 func (d Data) Render(w io.Writer, v interface{}) error {
 	if hw, ok := w.(http.ResponseWriter); ok {
@@ -84,6 +125,10 @@ func (d Data) Render(w io.Writer, v interface{}) error {
 
 // Render a HTML response.
 func (h HTML) Render(w io.Writer, binding interface{}) error {
+	if h.Streaming {
+		return h.renderStreaming(w, binding)
+	}
+
 	var buf *bytes.Buffer
 	if h.bp != nil {
 		// If we have a bufferpool, allocate from it
@@ -95,6 +140,11 @@ func (h HTML) Render(w io.Writer, binding interface{}) error {
 	if err != nil {
 		return err
 	}
+	if h.deferred != nil {
+		if err := h.deferred.writeTo(buf, nil); err != nil {
+			return err
+		}
+	}
 
 	if hw, ok := w.(http.ResponseWriter); ok {
 		h.Head.Write(hw)
@@ -104,6 +154,28 @@ func (h HTML) Render(w io.Writer, binding interface{}) error {
 	return nil
 }
 
+func (h HTML) renderStreaming(w io.Writer, binding interface{}) error {
+	s := h.stream
+	if s == nil {
+		s = &htmlStream{}
+	}
+	s.start(w, h.Head)
+
+	if err := h.Templates.ExecuteTemplate(s.w, h.Name, binding); err != nil {
+		return s.streamErr(err)
+	}
+	if h.deferred != nil {
+		// Send the page before waiting for the deferred partials.
+		if err := s.flush(); err != nil {
+			return s.streamErr(err)
+		}
+		if err := h.deferred.writeTo(s.w, s.flush); err != nil {
+			return s.streamErr(err)
+		}
+	}
+	return s.streamErr(s.flush())
+}
+
 // Render a JSON response.
 func (j JSON) Render(w io.Writer, v interface{}) error {
 	if j.StreamingJSON {
@@ -220,3 +292,56 @@ func (x XML) Render(w io.Writer, v interface{}) error {
 	w.Write(result)
 	return nil
 }
+
+// Render a YAML response. The value is encoded the way JSON encodes it, so
+// json struct tags and json.Marshaler are honored.
+func (y YAML) Render(w io.Writer, v interface{}) error {
+	indent := y.Indent
+	if indent <= 0 {
+		indent = 2
+	}
+
+	var buf bytes.Buffer
+	if err := encodeYAML(&buf, v, indent); err != nil {
+		return err
+	}
+
+	// YAML marshaled fine, write out the result.
+	if hw, ok := w.(http.ResponseWriter); ok {
+		y.Head.Write(hw)
+	}
+	buf.WriteTo(w)
+	return nil
+}
+
+// Render a MessagePack response. The value is encoded the way JSON encodes
+// it, so json struct tags and json.Marshaler are honored.
+func (m MsgPack) Render(w io.Writer, v interface{}) error {
+	result, err := encodeMsgPack(v)
+	if err != nil {
+		return err
+	}
+
+	// MessagePack marshaled fine, write out the result.
+	if hw, ok := w.(http.ResponseWriter); ok {
+		m.Head.Write(hw)
+	}
+	w.Write(result)
+	return nil
+}
+
+// Render a CBOR response. The value is encoded the way JSON encodes it, so
+// json struct tags and json.Marshaler are honored.
+func (c CBOR) Render(w io.Writer, v interface{}) error {
+	result, err := encodeCBOR(v)
+	if err != nil {
+		return err
+	}
+
+	// CBOR marshaled fine, write out the result.
+	if hw, ok := w.(http.ResponseWriter); ok {
+		c.Head.Write(hw)
+	}
+	w.Write(result)
+	return nil
+}
@@ -0,0 +1,207 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// parseErrorRe matches the location prefix text/template adds to parse errors.
+var parseErrorRe = regexp.MustCompile(`^template: [^:]*:(\d+): (.*)$`)
+
+// TemplateError describes a template file that failed to load or parse.
+type TemplateError struct {
+	// Path of the template file.
+	Path string
+	// Line the parse error was found on, or 0 if the file could not be read.
+	Line int
+	// Message describing the error, without location information.
+	Message string
+	// Err is the underlying error.
+	Err error
+}
+
+func newTemplateError(path string, err error) *TemplateError {
+	e := &TemplateError{
+		Path:    path,
+		Message: err.Error(),
+		Err:     err,
+	}
+
+	if m := parseErrorRe.FindStringSubmatch(e.Message); m != nil {
+		e.Line, _ = strconv.Atoi(m[1])
+		e.Message = m[2]
+	}
+	return e
+}
+
+func (e *TemplateError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", e.Path, e.Line, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// Unwrap returns the underlying error.
+func (e *TemplateError) Unwrap() error {
+	return e.Err
+}
+
+// CompileError is returned when one or more template files fail to compile.
+// It lists every failing file, not only the first one.
+type CompileError struct {
+	Errors []*TemplateError
+}
+
+func (e *CompileError) add(path string, err error) {
+	e.Errors = append(e.Errors, newTemplateError(path, err))
+}
+
+// orNil returns nil instead of an empty CompileError.
+func (e *CompileError) orNil() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *CompileError) Error() string {
+	lines := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		lines[i] = err.Error()
+	}
+
+	return fmt.Sprintf("render: %d template(s) failed to compile:\n%s", len(e.Errors), strings.Join(lines, "\n"))
+}
+
+// RenderError describes a render that failed. It is passed to
+// Options.ErrorHandler.
+type RenderError struct {
+	// Engine that failed to render, or nil if the render failed before an
+	// Engine was set up, e.g. for a fragment that does not exist.
+	Engine Engine
+	// Data the Engine was rendering.
+	Data interface{}
+	// Err is the error returned by the Engine.
+	Err error
+	// Status the error is rendered with by default, e.g.
+	// http.StatusNotAcceptable if Negotiate found no match.
+	Status int
+	// HeadersWritten reports whether the response headers were already sent.
+	// If so, the status can no longer be changed and a clean error page is not
+	// possible anymore.
+	HeadersWritten bool
+}
+
+func (e *RenderError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the error returned by the Engine.
+func (e *RenderError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorHandler handles a failed render, e.g. by rendering an error page. Its
+// result is returned by the render call, so it can return nil to mark the
+// error as handled or e.Err to re-raise it. Renders started by an
+// ErrorHandler with the writer it was passed are not handed back to it: if
+// they fail as well, the error is rendered the default way.
+type ErrorHandler func(w io.Writer, e *RenderError) error
+
+// failRender passes a render that failed before its Engine ran on to
+// Options.ErrorHandler, or renders err with the given status if there is
+// none.
+func (r *Render) failRender(w io.Writer, e Engine, data interface{}, err error, status int) error {
+	if r.opt.ErrorHandler != nil && !inErrorHandler(w) {
+		return r.handleError(w, &RenderError{
+			Engine: e,
+			Data:   data,
+			Err:    err,
+			Status: status,
+		})
+	}
+
+	r.renderError(w, err, status)
+	return err
+}
+
+// handleError passes a failed render on to Options.ErrorHandler, with a
+// writer that marks the renders it starts.
+func (r *Render) handleError(w io.Writer, e *RenderError) error {
+	if hw, ok := w.(http.ResponseWriter); ok {
+		return r.opt.ErrorHandler(&errorHandlerResponseWriter{ResponseWriter: hw}, e)
+	}
+	return r.opt.ErrorHandler(errorHandlerWriter{Writer: w}, e)
+}
+
+// inErrorHandler reports whether w is, or wraps, the writer passed to
+// Options.ErrorHandler.
+func inErrorHandler(w io.Writer) bool {
+	for {
+		switch v := w.(type) {
+		case errorHandlerWriter, *errorHandlerResponseWriter:
+			return true
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = v.Unwrap()
+		default:
+			return false
+		}
+	}
+}
+
+// errorHandlerWriter is passed to Options.ErrorHandler in place of a writer
+// that is not an http.ResponseWriter.
+type errorHandlerWriter struct {
+	io.Writer
+}
+
+// errorHandlerResponseWriter is passed to Options.ErrorHandler in place of
+// an http.ResponseWriter.
+type errorHandlerResponseWriter struct {
+	http.ResponseWriter
+}
+
+// Flush implements http.Flusher if the underlying ResponseWriter does.
+func (e *errorHandlerResponseWriter) Flush() {
+	if f, ok := e.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying ResponseWriter, for http.ResponseController.
+func (e *errorHandlerResponseWriter) Unwrap() http.ResponseWriter {
+	return e.ResponseWriter
+}
+
+// headerTracker records whether the headers of a response were written.
+type headerTracker struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (t *headerTracker) WriteHeader(status int) {
+	t.wroteHeader = true
+	t.ResponseWriter.WriteHeader(status)
+}
+
+func (t *headerTracker) Write(b []byte) (int, error) {
+	t.wroteHeader = true
+	return t.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher if the underlying ResponseWriter does.
+func (t *headerTracker) Flush() {
+	if f, ok := t.ResponseWriter.(http.Flusher); ok {
+		t.wroteHeader = true
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying ResponseWriter, for http.ResponseController.
+func (t *headerTracker) Unwrap() http.ResponseWriter {
+	return t.ResponseWriter
+}
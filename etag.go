@@ -0,0 +1,183 @@
+package render
+
+import (
+	"bytes"
+	"encoding/hex"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ETagMode selects whether and which kind of ETag is generated for a response.
+type ETagMode int
+
+const (
+	// ETagDefault uses the mode of Options.ETag. As an Options value it
+	// disables ETags.
+	ETagDefault ETagMode = iota
+	// ETagDisabled does not generate an ETag.
+	ETagDisabled
+	// ETagStrong generates a strong ETag from the rendered bytes.
+	ETagStrong
+	// ETagWeak generates a weak ETag from the rendered bytes.
+	ETagWeak
+)
+
+// conditionalWriter buffers a response to answer a conditional request. It
+// adds an ETag computed from the body, and replaces the response with 304 Not
+// Modified if the client already has it.
+type conditionalWriter struct {
+	http.ResponseWriter
+	req          *http.Request
+	mode         ETagMode
+	lastModified time.Time
+
+	status int
+	buf    bytes.Buffer
+}
+
+// conditional wraps w to answer conditional requests, unless the response is
+// streamed or neither an ETag nor a modification time is available.
+func (r *Render) conditional(w io.Writer, req *http.Request, stream bool, opt RequestOptions) (io.Writer, func() error) {
+	mode := opt.ETag
+	if mode == ETagDefault {
+		mode = r.opt.ETag
+	}
+
+	hw, ok := w.(http.ResponseWriter)
+	if !ok || stream || ((mode == ETagDefault || mode == ETagDisabled) && opt.LastModified.IsZero() && hw.Header().Get("Last-Modified") == "") {
+		return w, func() error { return nil }
+	}
+
+	cw := &conditionalWriter{
+		ResponseWriter: hw,
+		req:            req,
+		mode:           mode,
+		lastModified:   opt.LastModified,
+	}
+	return cw, cw.close
+}
+
+func (c *conditionalWriter) WriteHeader(status int) {
+	if c.status == 0 {
+		c.status = status
+	}
+}
+
+func (c *conditionalWriter) Write(b []byte) (int, error) {
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	return c.buf.Write(b)
+}
+
+// Unwrap returns the underlying ResponseWriter, for http.ResponseController.
+func (c *conditionalWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+// close sends the buffered response, or 304 Not Modified instead if it
+// matches the preconditions of the request.
+func (c *conditionalWriter) close() error {
+	// Nothing was rendered, e.g. because of an error.
+	if c.status == 0 {
+		return nil
+	}
+
+	h := c.ResponseWriter.Header()
+	if c.status == http.StatusOK {
+		if h.Get("ETag") == "" && (c.mode == ETagStrong || c.mode == ETagWeak) {
+			h.Set("ETag", computeETag(c.buf.Bytes(), c.mode == ETagWeak))
+		}
+		// The ETag of a compressed response is weak, which has to be decided
+		// here as a 304 is never compressed.
+		if cw, ok := c.ResponseWriter.(*compressWriter); ok && cw.willCompress(c.status, c.buf.Len()) {
+			weakenETag(h)
+		}
+		if !c.lastModified.IsZero() {
+			h.Set("Last-Modified", c.lastModified.UTC().Format(http.TimeFormat))
+		}
+
+		if notModified(c.req, h) {
+			// Same as net/http does for 304 responses.
+			h.Del(ContentType)
+			h.Del(ContentLength)
+			h.Del(ContentEncoding)
+			if h.Get("ETag") != "" {
+				h.Del("Last-Modified")
+			}
+			c.ResponseWriter.WriteHeader(http.StatusNotModified)
+			return nil
+		}
+	}
+
+	c.ResponseWriter.WriteHeader(c.status)
+	_, err := c.buf.WriteTo(c.ResponseWriter)
+	return err
+}
+
+// computeETag returns an ETag for the given bytes.
+func computeETag(b []byte, weak bool) string {
+	h := fnv.New64a()
+	h.Write(b)
+	etag := `"` + hex.EncodeToString(h.Sum(nil)) + `"`
+	if weak {
+		return "W/" + etag
+	}
+	return etag
+}
+
+// notModified reports whether a GET or HEAD request can be answered with 304
+// Not Modified, given the ETag and Last-Modified headers of the response.
+// If-None-Match takes precedence over If-Modified-Since, see RFC 7232.
+func notModified(req *http.Request, h http.Header) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+
+	if inm := req.Header.Get("If-None-Match"); inm != "" {
+		return etagMatch(inm, h.Get("ETag"))
+	}
+
+	ims := req.Header.Get("If-Modified-Since")
+	lastModified := h.Get("Last-Modified")
+	if ims == "" || lastModified == "" {
+		return false
+	}
+
+	since, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	modified, err := http.ParseTime(lastModified)
+	if err != nil {
+		return false
+	}
+	return !modified.After(since)
+}
+
+// etagMatch reports whether an If-None-Match header matches the ETag, using
+// the weak comparison.
+func etagMatch(ifNoneMatch, etag string) bool {
+	if etag == "" {
+		return false
+	}
+
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// weakenETag marks a strong ETag of the response as weak, as the response is
+// no longer byte-for-byte the representation it was computed for.
+func weakenETag(h http.Header) {
+	if etag := h.Get("ETag"); strings.HasPrefix(etag, `"`) {
+		h.Set("ETag", "W/"+etag)
+	}
+}
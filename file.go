@@ -0,0 +1,210 @@
+package render
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrNoFileDirectory is returned by File when Options.FileDirectory is not set.
+var ErrNoFileDirectory = errors.New("render: Options.FileDirectory is not set")
+
+// FileOptions is a struct for overriding some rendering Options for a
+// specific Download or File call.
+type FileOptions struct {
+	// Filename sent in the Content-Disposition header. Download sends none if
+	// it is empty, File defaults to the base name of the path.
+	Filename string
+	// Inline asks the browser to display the file instead of saving it.
+	Inline bool
+	// ContentType of the file. Defaults to the type of the file name
+	// extension, or else the type sniffed from the content.
+	ContentType string
+	// LastModified is sent as the Last-Modified header and used to answer
+	// conditional and If-Range requests. File defaults to the modification
+	// time of the file.
+	LastModified time.Time
+	// Headers and Cookies sent with the response, see Head. An ETag set here
+	// is also used to answer conditional and If-Range requests.
+	Headers http.Header
+	Cookies []*http.Cookie
+}
+
+// File built-in renderer. It serves an io.ReadSeeker with http.ServeContent,
+// which answers Range, If-Range and conditional requests with the matching
+// status, so the Status of the Head is not used. The content type is detected
+// if the Head has none.
+//
+// A read error after the response was started is returned as a *StreamError
+// that counts the bytes read.
+type File struct {
+	Head
+	// Request that is answered. It is required.
+	Request *http.Request
+	// Name of the file, whose extension is used to detect the content type.
+	Name         string
+	LastModified time.Time
+}
+
+// Render a file response.
+func (f File) Render(w io.Writer, v interface{}) error {
+	content, ok := v.(io.ReadSeeker)
+	if !ok {
+		return fmt.Errorf("render: File expects an io.ReadSeeker, got %T", v)
+	}
+
+	hw, ok := w.(http.ResponseWriter)
+	if !ok {
+		_, err := io.Copy(w, content)
+		return err
+	}
+
+	f.Head.writeHeaders(hw)
+	if len(f.ContentType) > 0 {
+		hw.Header().Set(ContentType, f.ContentType)
+	}
+
+	fr := &fileReader{ReadSeeker: content}
+	http.ServeContent(hw, f.Request, f.Name, f.LastModified, fr)
+	if fr.err != nil {
+		return &StreamError{Err: fr.err, Written: fr.read}
+	}
+	return nil
+}
+
+// fileReader keeps the first read error, which http.ServeContent drops.
+type fileReader struct {
+	io.ReadSeeker
+	read int
+	err  error
+}
+
+func (f *fileReader) Read(p []byte) (int, error) {
+	n, err := f.ReadSeeker.Read(p)
+	f.read += n
+	if err != nil && err != io.EOF && f.err == nil {
+		f.err = err
+	}
+	return n, err
+}
+
+// Download serves content as a file to save, or to display if
+// FileOptions.Inline is set. Range and If-Range requests are answered with
+// 206 Partial Content, and conditional requests with 304 Not Modified.
+func (r *Render) Download(w io.Writer, req *http.Request, content io.ReadSeeker, fileOpt ...FileOptions) error {
+	var opt FileOptions
+	if len(fileOpt) > 0 {
+		opt = fileOpt[0]
+	}
+
+	return r.file(w, req, content, opt)
+}
+
+// File serves a file of Options.FS, or of Options.FileSystem, like Download.
+// The slash separated name is looked up below Options.FileDirectory, and
+// names that would escape it, such as "../secret", are answered with 404 Not
+// Found like files that do not exist and directories. Without a
+// FileDirectory, every name is answered with 404 Not Found and
+// ErrNoFileDirectory is returned.
+func (r *Render) File(w io.Writer, req *http.Request, name string, fileOpt ...FileOptions) error {
+	var opt FileOptions
+	if len(fileOpt) > 0 {
+		opt = fileOpt[0]
+	}
+	if len(opt.Filename) == 0 {
+		opt.Filename = path.Base(name)
+	}
+
+	content, modTime, err := r.openFile(name)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, fs.ErrNotExist) || errors.Is(err, ErrNoFileDirectory) {
+			status = http.StatusNotFound
+		}
+		return r.failRender(w, nil, name, err, status)
+	}
+	if c, ok := content.(io.Closer); ok {
+		defer c.Close()
+	}
+	if opt.LastModified.IsZero() {
+		opt.LastModified = modTime
+	}
+
+	return r.file(w, req, content, opt)
+}
+
+func (r *Render) file(w io.Writer, req *http.Request, content io.ReadSeeker, opt FileOptions) error {
+	if err := req.Context().Err(); err != nil {
+		return err
+	}
+
+	dispositionType := "attachment"
+	if opt.Inline {
+		dispositionType = "inline"
+	}
+
+	head := Head{
+		ContentType: opt.ContentType,
+		Headers:     http.Header{"Content-Disposition": {contentDisposition(dispositionType, opt.Filename)}},
+		Cookies:     opt.Cookies,
+	}
+	for k, v := range opt.Headers {
+		head.Headers[k] = v
+	}
+
+	f := File{
+		Head:         head,
+		Request:      req,
+		Name:         opt.Filename,
+		LastModified: opt.LastModified,
+	}
+
+	return r.Render(w, f, content)
+}
+
+// openFile opens a file below Options.FileDirectory of Options.FS or
+// Options.FileSystem, along with its modification time. Files of a FileSystem
+// that is not also an http.FileSystem are read into memory.
+func (r *Render) openFile(name string) (io.ReadSeeker, time.Time, error) {
+	if len(r.opt.FileDirectory) == 0 {
+		return nil, time.Time{}, ErrNoFileDirectory
+	}
+	rel := strings.TrimPrefix(name, "/")
+	if !fs.ValidPath(rel) {
+		return nil, time.Time{}, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	name = path.Join(r.opt.FileDirectory, rel)
+
+	var fsys http.FileSystem
+	if r.opt.FS != nil {
+		fsys = http.FS(r.opt.FS)
+	} else if hfs, ok := r.opt.FileSystem.(http.FileSystem); ok {
+		fsys = hfs
+	} else {
+		b, err := r.opt.FileSystem.ReadFile(name)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		return bytes.NewReader(b), time.Time{}, nil
+	}
+
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	info, err := f.Stat()
+	if err == nil && info.IsDir() {
+		err = &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if err != nil {
+		f.Close()
+		return nil, time.Time{}, err
+	}
+	return f, info.ModTime(), nil
+}
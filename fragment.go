@@ -0,0 +1,62 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrFragmentNotFound is returned when a page does not define the fragment
+// that should be rendered.
+var ErrFragmentNotFound = errors.New("render: fragment not found")
+
+// IsFragmentRequest reports whether a request only asks for a fragment of a
+// page, as htmx requests do with the HX-Request header. Boosted htmx requests
+// ask for the whole page.
+func IsFragmentRequest(req *http.Request) bool {
+	return req.Header.Get("HX-Request") == "true" && req.Header.Get("HX-Boosted") != "true"
+}
+
+// Fragment renders only the given {{ define }} block of a page, without a
+// layout. The block is looked up as "<fragment>-<page>" first, like partials
+// and sections, and then by its own name if the page's file defines it.
+func (r *Render) Fragment(w io.Writer, status int, name, fragment string, binding interface{}, htmlOpt ...HTMLOptions) error {
+	return r.html(w, nil, status, name, fragment, binding, htmlOpt)
+}
+
+// FragmentRequest is like HTMLRequest, but renders only the given fragment of
+// the page if IsFragmentRequest reports that the request asks for one.
+func (r *Render) FragmentRequest(w io.Writer, req *http.Request, status int, name, fragment string, binding interface{}, htmlOpt ...HTMLOptions) error {
+	if hw, ok := w.(http.ResponseWriter); ok {
+		addVary(hw.Header(), "HX-Request")
+	}
+	if !IsFragmentRequest(req) {
+		return r.HTMLRequest(w, req, status, name, binding, htmlOpt...)
+	}
+
+	opt := r.prepareHTMLOptions(htmlOpt)
+	reqOpt := RequestOptions{
+		ETag:         opt.ETag,
+		LastModified: opt.LastModified,
+	}
+
+	return r.serve(w, req, opt.Streaming, opt.Streaming, reqOpt, func(w io.Writer) error {
+		return r.html(w, req, status, name, fragment, binding, htmlOpt)
+	})
+}
+
+// lookupFragment returns the template name of a fragment of a page.
+func (c *templateClone) lookupFragment(page, fragment string) (string, error) {
+	if c.tpl.Lookup(page) == nil {
+		return "", fmt.Errorf("html/template: %q is undefined", page)
+	}
+
+	if fullName := sectionName(fragment, page); c.tpl.Lookup(fullName) != nil {
+		return fullName, nil
+	}
+	if t := c.tpl.Lookup(fragment); t != nil && t.Tree != nil && t.Tree.ParseName == page {
+		return fragment, nil
+	}
+	return "", fmt.Errorf("%w: %q is not defined in %q", ErrFragmentNotFound, fragment, page)
+}
@@ -1,8 +1,13 @@
 package render
 
 import (
+	"io/fs"
 	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
 	"path/filepath"
+	"strings"
 )
 
 type FileSystem interface {
@@ -19,3 +24,117 @@ func (LocalFileSystem) Walk(root string, walkFn filepath.WalkFunc) error {
 func (LocalFileSystem) ReadFile(filename string) ([]byte, error) {
 	return ioutil.ReadFile(filename)
 }
+
+// Open makes LocalFileSystem an http.FileSystem, so that Render.File streams
+// files instead of reading them into memory. Unlike http.Dir, it opens names
+// as they are: Render.File only passes names below Options.FileDirectory.
+func (LocalFileSystem) Open(name string) (http.File, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// templateSource lists and reads template files. It adapts Options.FS,
+// Options.FileSystem and the Asset functions to a single compile path.
+type templateSource interface {
+	// walk calls fn for every file below the template directory with its
+	// path, its slash separated path relative to that directory and its
+	// FileInfo, which is nil if the source has none.
+	walk(fn func(path, rel string, info fs.FileInfo) error) error
+	readFile(path string) ([]byte, error)
+}
+
+// templateSource returns the adapter for the configured template source.
+func (r *Render) templateSource() templateSource {
+	switch {
+	case r.opt.FS != nil:
+		return fsSource{fsys: r.opt.FS, dir: path.Clean(filepath.ToSlash(r.opt.Directory))}
+	case r.opt.Asset != nil && r.opt.AssetNames != nil:
+		return assetSource{asset: r.opt.Asset, assetNames: r.opt.AssetNames, dir: r.opt.Directory}
+	default:
+		return fileSystemSource{fs: r.opt.FileSystem, dir: r.opt.Directory}
+	}
+}
+
+// fsSource loads templates from an fs.FS, such as an embed.FS.
+type fsSource struct {
+	fsys fs.FS
+	dir  string
+}
+
+func (s fsSource) walk(fn func(path, rel string, info fs.FileInfo) error) error {
+	return fs.WalkDir(s.fsys, s.dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+
+		rel := p
+		if s.dir != "." {
+			rel = strings.TrimPrefix(p, s.dir+"/")
+		}
+		info, _ := d.Info()
+		return fn(p, rel, info)
+	})
+}
+
+func (s fsSource) readFile(path string) ([]byte, error) {
+	return fs.ReadFile(s.fsys, path)
+}
+
+// fileSystemSource loads templates through a FileSystem.
+type fileSystemSource struct {
+	fs  FileSystem
+	dir string
+}
+
+func (s fileSystemSource) walk(fn func(path, rel string, info fs.FileInfo) error) error {
+	return s.fs.Walk(s.dir, func(path string, info os.FileInfo, err error) error {
+		// Fix same-extension-dirs bug: some dir might be named to: "users.tmpl", "local.html".
+		// These dirs should be excluded as they are not valid golang templates, but files under
+		// them should be treat as normal.
+		// If is a dir, return immediately (dir is not a valid golang template).
+		if info == nil || info.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.dir, path)
+		if err != nil {
+			return err
+		}
+		return fn(path, filepath.ToSlash(rel), info)
+	})
+}
+
+func (s fileSystemSource) readFile(path string) ([]byte, error) {
+	return s.fs.ReadFile(path)
+}
+
+// assetSource loads templates through the Asset and AssetNames functions.
+type assetSource struct {
+	asset      func(name string) ([]byte, error)
+	assetNames func() []string
+	dir        string
+}
+
+func (s assetSource) walk(fn func(path, rel string, info fs.FileInfo) error) error {
+	for _, path := range s.assetNames() {
+		if !strings.HasPrefix(path, s.dir) {
+			continue
+		}
+
+		rel, err := filepath.Rel(s.dir, path)
+		if err != nil {
+			return err
+		}
+		if err := fn(path, filepath.ToSlash(rel), nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s assetSource) readFile(path string) ([]byte, error) {
+	return s.asset(path)
+}
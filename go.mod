@@ -1,5 +1,5 @@
 module github.com/unrolled/render
 
-go 1.12
+go 1.16
 
 require github.com/eknkc/amber v0.0.0-20171010120322-cdade1c07385
@@ -5,6 +5,7 @@ package render
 import (
 	"fmt"
 	"html/template"
+	"net/http"
 )
 
 // Included helper functions for use when rendering HTML.
@@ -20,7 +21,35 @@ var helperFuncs = template.FuncMap{
 	"partial": func() (string, error) {
 		return "", fmt.Errorf("block called with no layout defined")
 	},
+	"partialWith": func(name string, data interface{}) (string, error) {
+		return "", fmt.Errorf("partialWith called outside of a render")
+	},
+	"dict": dict,
+	"component": func(name string, props ...interface{}) (string, error) {
+		return "", fmt.Errorf("component called outside of a render")
+	},
+	"props": func(names ...string) (string, error) {
+		return "", nil
+	},
+	"slots": func(names ...string) (string, error) {
+		return "", nil
+	},
 	"current": func() (string, error) {
 		return "", nil
 	},
+	"section": func(name string, fallback ...interface{}) (string, error) {
+		return "", fmt.Errorf("section called with no layout defined")
+	},
+	"layout": func(parent string) (string, error) {
+		return "", nil
+	},
+	"deferred": func() (string, error) {
+		return "", fmt.Errorf("deferred called with no layout defined")
+	},
+	"request": func() (*http.Request, error) {
+		return nil, nil
+	},
+	"requestData": func(key string) (interface{}, error) {
+		return nil, nil
+	},
 }
@@ -0,0 +1,155 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"sort"
+	"strings"
+	"text/template/parse"
+)
+
+// layoutParents returns the parent layouts that template files declare with
+// {{ layout "name" }}, by template name. There are none if Options.Funcs
+// has a layout func of its own.
+func layoutParents(opt *Options, templates *template.Template, files map[string]templateFile) map[string]string {
+	parents := map[string]string{}
+	if !builtinFunc(opt, "layout") {
+		return parents
+	}
+	for _, file := range files {
+		if parent, ok := parentLayout(templates.Lookup(file.name)); ok {
+			parents[file.name] = parent
+		}
+	}
+	return parents
+}
+
+// parentLayout returns the parent declared by a layout call at the top level
+// of a template.
+func parentLayout(t *template.Template) (string, bool) {
+	args, ok := declaration(t, "layout")
+	if !ok || len(args) != 1 {
+		return "", false
+	}
+	return args[0], true
+}
+
+// declaration returns the arguments of the first call to the given func at
+// the top level of a template. Only calls with constant string arguments are
+// considered, as they are read when the templates are compiled.
+func declaration(t *template.Template, fn string) ([]string, bool) {
+	if t == nil || t.Tree == nil || t.Tree.Root == nil {
+		return nil, false
+	}
+
+	for _, node := range t.Tree.Root.Nodes {
+		action, ok := node.(*parse.ActionNode)
+		if !ok || action.Pipe == nil || len(action.Pipe.Cmds) == 0 {
+			continue
+		}
+		if name, args, ok := constantCall(action.Pipe.Cmds[0]); ok && name == fn {
+			return args, true
+		}
+	}
+	return nil, false
+}
+
+// constantCall returns the func called by a command and its arguments, if
+// they are all constant strings.
+func constantCall(cmd *parse.CommandNode) (string, []string, bool) {
+	if len(cmd.Args) == 0 {
+		return "", nil, false
+	}
+	ident, ok := cmd.Args[0].(*parse.IdentifierNode)
+	if !ok {
+		return "", nil, false
+	}
+
+	args := make([]string, 0, len(cmd.Args)-1)
+	for _, arg := range cmd.Args[1:] {
+		s, ok := arg.(*parse.StringNode)
+		if !ok {
+			return "", nil, false
+		}
+		args = append(args, s.Text)
+	}
+	return ident.Ident, args, true
+}
+
+// checkLayouts adds an error to errs for every template file whose layout
+// chain refers to a missing layout or runs into a cycle.
+func checkLayouts(opt *Options, templates *template.Template, files map[string]templateFile, errs *CompileError) {
+	parents := layoutParents(opt, templates, files)
+
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		name := files[path].name
+		parent, ok := parents[name]
+		if !ok {
+			continue
+		}
+
+		if templates.Lookup(parent) == nil {
+			errs.add(path, fmt.Errorf("parent layout %q of %q is not defined", parent, name))
+			continue
+		}
+
+		chain := []string{name}
+		for ok {
+			if indexOf(chain, parent) >= 0 {
+				chain = append(chain, parent)
+				errs.add(path, fmt.Errorf("layout chain forms a cycle: %s", strings.Join(chain, " -> ")))
+				break
+			}
+			chain = append(chain, parent)
+			parent, ok = parents[parent]
+		}
+	}
+}
+
+// layoutChain returns the layouts a page is rendered within, starting with
+// the outermost one and ending with the given layout.
+func (s *templateSet) layoutChain(layout string) []string {
+	chain := []string{layout}
+	for parent, ok := s.parents[layout]; ok && indexOf(chain, parent) < 0; parent, ok = s.parents[parent] {
+		chain = append(chain, parent)
+	}
+
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain
+}
+
+// sectionPrefix marks the templates a file defines as sections.
+const sectionPrefix = "section:"
+
+// addSections adds the sections defined in a template file under their names
+// for that file, so that {{ define "section:title" }} in the page "home" is
+// found as "title-home", the same name a partial "title" has for it.
+func addSections(templates *template.Template, name string, buf []byte) error {
+	if !bytes.Contains(buf, []byte(sectionPrefix)) {
+		return nil
+	}
+
+	for _, t := range templates.Templates() {
+		if t.Tree == nil || t.Tree.ParseName != name || !strings.HasPrefix(t.Name(), sectionPrefix) {
+			continue
+		}
+		if _, err := templates.AddParseTree(sectionName(strings.TrimPrefix(t.Name(), sectionPrefix), name), t.Tree); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sectionName returns the template name of a section filled by a page or layout.
+func sectionName(section, owner string) string {
+	return section + "-" + owner
+}
@@ -0,0 +1,204 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+)
+
+// ContentNDJSON header value for newline delimited JSON.
+const ContentNDJSON = "application/x-ndjson"
+
+// Iterator produces the values of a stream. It calls yield for every value
+// and must stop and return the error as soon as yield returns one, which
+// happens when the client is gone or the request's context is done.
+type Iterator func(yield func(v interface{}) error) error
+
+// StreamError is returned when a stream fails after it was started. As the
+// response is already under way, no error response is written for it.
+type StreamError struct {
+	// Err is the error that ended the stream.
+	Err error
+	// Written is the number of values that were sent before the error, or
+	// the number of bytes for HTML and files.
+	Written int
+}
+
+func (e *StreamError) Error() string {
+	return fmt.Sprintf("render: stream failed after %d value(s): %v", e.Written, e.Err)
+}
+
+// Unwrap returns the error that ended the stream.
+func (e *StreamError) Unwrap() error {
+	return e.Err
+}
+
+// NDJSON built-in renderer. It renders a channel, an Iterator, or a slice as
+// newline delimited JSON, one document per value, and flushes the response
+// as it goes.
+type NDJSON struct {
+	Head
+	// Context stops the stream when it is done. Defaults to context.Background().
+	Context context.Context
+	// FlushEvery is the number of lines sent per flush. Defaults to 1.
+	FlushEvery   int
+	UnEscapeHTML bool
+}
+
+// Render a NDJSON response.
+func (n NDJSON) Render(w io.Writer, v interface{}) error {
+	values, err := newValueStream(v)
+	if err != nil {
+		return err
+	}
+
+	ctx := n.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	flushEvery := n.FlushEvery
+	if flushEvery <= 0 {
+		flushEvery = 1
+	}
+	flusher, _ := w.(http.Flusher)
+
+	if hw, ok := w.(http.ResponseWriter); ok {
+		n.Head.Write(hw)
+	}
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	var buf bytes.Buffer
+	written := 0
+	err = values.each(ctx, func(v interface{}) error {
+		buf.Reset()
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(!n.UnEscapeHTML)
+		// The line is only written once it was encoded completely.
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		if _, err := buf.WriteTo(w); err != nil {
+			return err
+		}
+
+		written++
+		if flusher != nil && written%flushEvery == 0 {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if flusher != nil && written%flushEvery != 0 {
+		flusher.Flush()
+	}
+
+	if err != nil {
+		return &StreamError{Err: err, Written: written}
+	}
+	return nil
+}
+
+// valueStream iterates over the values of a channel, an Iterator or a slice.
+type valueStream struct {
+	v reflect.Value
+}
+
+func newValueStream(v interface{}) (*valueStream, error) {
+	switch it := v.(type) {
+	case Iterator:
+		return &valueStream{v: reflect.ValueOf(it)}, nil
+	case func(yield func(v interface{}) error) error:
+		return &valueStream{v: reflect.ValueOf(Iterator(it))}, nil
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Chan:
+		if rv.Type().ChanDir()&reflect.RecvDir == 0 {
+			return nil, fmt.Errorf("render: cannot receive from %T", v)
+		}
+	case reflect.Slice, reflect.Array:
+	default:
+		return nil, fmt.Errorf("render: expected a channel, an Iterator or a slice, got %T", v)
+	}
+	return &valueStream{v: rv}, nil
+}
+
+// each calls fn for every value until the values are exhausted, fn returns an
+// error, or ctx is done.
+func (s *valueStream) each(ctx context.Context, fn func(v interface{}) error) error {
+	switch s.v.Kind() {
+	case reflect.Func:
+		it := s.v.Interface().(Iterator)
+		return it(func(v interface{}) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			return fn(v)
+		})
+	case reflect.Chan:
+		cases := []reflect.SelectCase{
+			{Dir: reflect.SelectRecv, Chan: s.v},
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+		}
+		for {
+			chosen, v, ok := reflect.Select(cases)
+			if chosen == 1 {
+				return ctx.Err()
+			}
+			if !ok {
+				return nil
+			}
+			if err := fn(v.Interface()); err != nil {
+				return err
+			}
+		}
+	}
+
+	for i := 0; i < s.v.Len(); i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(s.v.Index(i).Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NDJSON writes out the values of a channel, an Iterator or a slice as
+// newline delimited JSON, flushing after every line.
+func (r *Render) NDJSON(w io.Writer, status int, v interface{}) error {
+	return r.ndjson(context.Background(), w, status, v, RequestOptions{})
+}
+
+// NDJSONRequest is like NDJSON, but stops the stream once the request's
+// context is done.
+func (r *Render) NDJSONRequest(w io.Writer, req *http.Request, status int, v interface{}, reqOpt ...RequestOptions) error {
+	opt := prepareRequestOptions(reqOpt)
+	return r.serve(w, req, true, true, opt, func(w io.Writer) error {
+		return r.ndjson(req.Context(), w, status, v, opt)
+	})
+}
+
+func (r *Render) ndjson(ctx context.Context, w io.Writer, status int, v interface{}, opt RequestOptions) error {
+	head := Head{
+		ContentType: r.opt.NDJSONContentType + r.compiledCharset,
+		Status:      status,
+		Headers:     opt.Headers,
+		Cookies:     opt.Cookies,
+	}
+
+	n := NDJSON{
+		Head:         head,
+		Context:      ctx,
+		UnEscapeHTML: r.opt.UnEscapeHTML,
+	}
+
+	return r.Render(w, n, v)
+}
@@ -0,0 +1,345 @@
+package render
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// contentXMLApplication is offered by Negotiate as an alias of the XML content type.
+const contentXMLApplication = "application/xml"
+
+// ErrNotAcceptable is returned by Negotiate when none of the offered media
+// types satisfies the request's Accept header.
+var ErrNotAcceptable = errors.New("render: no acceptable media type")
+
+// EngineFactory builds the Engine used for a negotiated response. The given
+// Head already carries the selected content type and status.
+type EngineFactory func(head Head) Engine
+
+// NegotiateOptions is a struct for overriding the content negotiation for a
+// specific Negotiate call.
+type NegotiateOptions struct {
+	// HTML template name to render when HTML is selected. HTML is only offered
+	// when this is set.
+	HTMLTemplate string
+	// HTML options used when HTML is selected.
+	HTML HTMLOptions
+	// Offers restricts the candidate media types and sets their order of
+	// preference. Defaults to every registered engine.
+	Offers []string
+	// Request options for the other content types. The HTML ones are taken
+	// from HTML.
+	Request RequestOptions
+}
+
+// negotiator renders a value for one negotiable media type.
+type negotiator struct {
+	contentType string
+	render      func(w io.Writer, req *http.Request, status int, v interface{}, opt NegotiateOptions) error
+	// accepts reports whether v can be rendered. Nil accepts every value.
+	accepts func(v interface{}) bool
+}
+
+// acceptRange is a single media range parsed from an Accept header.
+type acceptRange struct {
+	typ     string
+	subtype string
+	q       float64
+}
+
+func (r *Render) registerBuiltinEngines() {
+	r.negotiators = []negotiator{
+		{
+			contentType: mediaType(r.opt.JSONContentType),
+			render: func(w io.Writer, req *http.Request, status int, v interface{}, opt NegotiateOptions) error {
+				if r.isProto(v) && r.opt.ProtobufJSON != nil {
+					return r.ProtobufJSONRequest(w, req, status, v, opt.Request)
+				}
+				return r.JSONRequest(w, req, status, v, opt.Request)
+			},
+		},
+		{
+			contentType: mediaType(r.opt.XMLContentType),
+			render: func(w io.Writer, req *http.Request, status int, v interface{}, opt NegotiateOptions) error {
+				return r.XMLRequest(w, req, status, v, opt.Request)
+			},
+			accepts: isXML,
+		},
+		{
+			contentType: contentXMLApplication,
+			render: func(w io.Writer, req *http.Request, status int, v interface{}, opt NegotiateOptions) error {
+				return r.serve(w, req, false, false, opt.Request, func(w io.Writer) error {
+					return r.xmlAs(w, contentXMLApplication, status, v, opt.Request)
+				})
+			},
+			accepts: isXML,
+		},
+		{
+			contentType: mediaType(r.opt.HTMLContentType),
+			render: func(w io.Writer, req *http.Request, status int, v interface{}, opt NegotiateOptions) error {
+				return r.HTMLRequest(w, req, status, opt.HTMLTemplate, v, opt.HTML)
+			},
+		},
+		{
+			contentType: mediaType(r.opt.TextContentType),
+			render: func(w io.Writer, req *http.Request, status int, v interface{}, opt NegotiateOptions) error {
+				return r.TextRequest(w, req, status, textValue(v), opt.Request)
+			},
+		},
+		{
+			contentType: mediaType(r.opt.YAMLContentType),
+			render: func(w io.Writer, req *http.Request, status int, v interface{}, opt NegotiateOptions) error {
+				return r.YAMLRequest(w, req, status, v, opt.Request)
+			},
+		},
+		{
+			contentType: mediaType(r.opt.MsgPackContentType),
+			render: func(w io.Writer, req *http.Request, status int, v interface{}, opt NegotiateOptions) error {
+				return r.MsgPackRequest(w, req, status, v, opt.Request)
+			},
+		},
+		{
+			contentType: mediaType(r.opt.CBORContentType),
+			render: func(w io.Writer, req *http.Request, status int, v interface{}, opt NegotiateOptions) error {
+				return r.CBORRequest(w, req, status, v, opt.Request)
+			},
+		},
+		{
+			contentType: mediaType(r.opt.ProtobufContentType),
+			render: func(w io.Writer, req *http.Request, status int, v interface{}, opt NegotiateOptions) error {
+				return r.ProtobufRequest(w, req, status, v, opt.Request)
+			},
+		},
+	}
+}
+
+// RegisterEngine makes a custom Engine available to Negotiate under the given
+// content type. Engines registered later have a lower preference when the
+// client accepts several types equally. It is not safe to call RegisterEngine
+// while requests are being served.
+func (r *Render) RegisterEngine(contentType string, factory EngineFactory) {
+	r.negotiators = append(r.negotiators, negotiator{
+		contentType: mediaType(contentType),
+		render: func(w io.Writer, _ *http.Request, status int, v interface{}, _ NegotiateOptions) error {
+			head := Head{
+				ContentType: contentType,
+				Status:      status,
+			}
+
+			return r.Render(w, factory(head), v)
+		},
+	})
+}
+
+// Negotiate renders v with the registered engine whose content type best
+// matches the Accept header of req. It always adds Accept to the Vary header,
+// and answers with http.StatusNotAcceptable when nothing matches.
+func (r *Render) Negotiate(w io.Writer, req *http.Request, status int, v interface{}, negOpt ...NegotiateOptions) error {
+	var opt NegotiateOptions
+	if len(negOpt) > 0 {
+		opt = negOpt[0]
+	}
+
+	if hw, ok := w.(http.ResponseWriter); ok {
+		addVary(hw.Header(), "Accept")
+	}
+
+	candidates := r.negotiationCandidates(v, opt)
+	for {
+		offers := make([]string, len(candidates))
+		for i, n := range candidates {
+			offers[i] = n.contentType
+		}
+
+		i := negotiateContentType(req.Header.Get("Accept"), offers)
+		if i < 0 {
+			return r.failRender(w, nil, v, ErrNotAcceptable, http.StatusNotAcceptable)
+		}
+
+		// Fall through to the next acceptable offer when the selected engine
+		// cannot encode v. This is checked lazily as it may be expensive.
+		if n := candidates[i]; n.accepts != nil && !n.accepts(v) {
+			candidates = append(candidates[:i:i], candidates[i+1:]...)
+			continue
+		}
+
+		return candidates[i].render(w, req, status, v, opt)
+	}
+}
+
+// negotiationCandidates returns the negotiators that are able to render v,
+// in order of server preference.
+func (r *Render) negotiationCandidates(v interface{}, opt NegotiateOptions) []negotiator {
+	available := make([]negotiator, 0, len(r.negotiators))
+	for _, n := range r.negotiators {
+		if n.contentType == contentXMLApplication && strings.EqualFold(mediaType(r.opt.XMLContentType), contentXMLApplication) {
+			continue
+		}
+		switch n.contentType {
+		case mediaType(r.opt.HTMLContentType):
+			if len(opt.HTMLTemplate) == 0 {
+				continue
+			}
+		case mediaType(r.opt.TextContentType):
+			if !isText(v) {
+				continue
+			}
+		case mediaType(r.opt.ProtobufContentType):
+			if !r.isProto(v) {
+				continue
+			}
+		}
+		available = append(available, n)
+	}
+
+	if len(opt.Offers) == 0 {
+		return available
+	}
+
+	candidates := make([]negotiator, 0, len(opt.Offers))
+	for _, offer := range opt.Offers {
+		for _, n := range available {
+			if strings.EqualFold(n.contentType, mediaType(offer)) {
+				candidates = append(candidates, n)
+				break
+			}
+		}
+	}
+	return candidates
+}
+
+// negotiateContentType returns the index of the offer that best matches the
+// given Accept header, or -1 if none is acceptable. An empty header accepts
+// the first offer.
+func negotiateContentType(accept string, offers []string) int {
+	if len(offers) == 0 {
+		return -1
+	}
+	if strings.TrimSpace(accept) == "" {
+		return 0
+	}
+
+	ranges := parseAccept(accept)
+	best, bestQ := -1, 0.0
+	for i, offer := range offers {
+		q := acceptQuality(ranges, offer)
+		if q > bestQ {
+			best, bestQ = i, q
+		}
+	}
+	return best
+}
+
+// acceptQuality returns the quality value of the most specific media range
+// matching offer.
+func acceptQuality(ranges []acceptRange, offer string) float64 {
+	typ, subtype := splitMediaType(offer)
+
+	q, specificity := 0.0, -1
+	for _, ar := range ranges {
+		s := -1
+		switch {
+		case ar.typ == typ && ar.subtype == subtype:
+			s = 2
+		case ar.typ == typ && ar.subtype == "*":
+			s = 1
+		case ar.typ == "*" && ar.subtype == "*":
+			s = 0
+		}
+		if s > specificity {
+			q, specificity = ar.q, s
+		}
+	}
+	return q
+}
+
+// parseAccept parses the media ranges of an Accept header.
+func parseAccept(accept string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		value, q := parseQuality(part)
+		typ, subtype := splitMediaType(value)
+		if typ == "" {
+			continue
+		}
+		ranges = append(ranges, acceptRange{typ: typ, subtype: subtype, q: q})
+	}
+	return ranges
+}
+
+// parseQuality splits an element of an Accept-style header into its value
+// and its quality, which defaults to 1.
+func parseQuality(s string) (string, float64) {
+	params := strings.Split(s, ";")
+	q := 1.0
+	for _, param := range params[1:] {
+		kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+		if len(kv) != 2 || strings.ToLower(kv[0]) != "q" {
+			continue
+		}
+		if v, err := strconv.ParseFloat(kv[1], 64); err == nil && v >= 0 && v <= 1 {
+			q = v
+		}
+	}
+	return strings.TrimSpace(params[0]), q
+}
+
+// splitMediaType lower-cases a media type and splits it into type and subtype.
+func splitMediaType(s string) (string, string) {
+	s = strings.ToLower(mediaType(s))
+	i := strings.Index(s, "/")
+	if i < 0 {
+		if s == "*" {
+			return "*", "*"
+		}
+		return "", ""
+	}
+	return s[:i], s[i+1:]
+}
+
+// mediaType strips any parameters from a content type.
+func mediaType(contentType string) string {
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return strings.TrimSpace(contentType)
+}
+
+// addVary adds field to the Vary header unless it is already listed.
+func addVary(h http.Header, field string) {
+	for _, v := range h["Vary"] {
+		for _, f := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(f), field) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", field)
+}
+
+func isText(v interface{}) bool {
+	switch v.(type) {
+	case string, fmt.Stringer:
+		return true
+	}
+	return false
+}
+
+// isXML reports whether v can be encoded by encoding/xml, which rejects
+// maps among others.
+func isXML(v interface{}) bool {
+	return xml.NewEncoder(io.Discard).Encode(v) == nil
+}
+
+func textValue(v interface{}) string {
+	if s, ok := v.(fmt.Stringer); ok {
+		return s.String()
+	}
+	s, _ := v.(string)
+	return s
+}
@@ -0,0 +1,215 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"sort"
+)
+
+const (
+	// ContentProblemJSON header value for RFC 7807 JSON problem details.
+	ContentProblemJSON = "application/problem+json"
+	// ContentProblemXML header value for RFC 7807 XML problem details.
+	ContentProblemXML = "application/problem+xml"
+
+	// problemNamespace is the XML namespace of RFC 7807 problem details.
+	problemNamespace = "urn:ietf:rfc:7807"
+)
+
+// Problem is an RFC 7807 problem details document.
+type Problem struct {
+	// Type is a URI reference that identifies the problem type.
+	Type string
+	// Title is a short, human-readable summary of the problem type.
+	Title string
+	// Status is the HTTP status code.
+	Status int
+	// Detail is a human-readable explanation of this occurrence of the problem.
+	Detail string
+	// Instance is a URI reference that identifies this occurrence of the problem.
+	Instance string
+	// Extensions holds additional members. Members named like one of the
+	// above are ignored.
+	Extensions map[string]interface{}
+
+	// Headers and Cookies sent with the response, see Head. They are not
+	// part of the document.
+	Headers http.Header
+	Cookies []*http.Cookie
+}
+
+// problemMembers are the JSON members defined by RFC 7807, in document order.
+type problemMembers struct {
+	Type     string `json:"type,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Status   int    `json:"status,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+}
+
+// extensionNames returns the names of the extension members in sorted order.
+func (p Problem) extensionNames() []string {
+	names := make([]string, 0, len(p.Extensions))
+	for name := range p.Extensions {
+		switch name {
+		case "type", "title", "status", "detail", "instance":
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// MarshalJSON encodes the problem with its extension members inlined.
+func (p Problem) MarshalJSON() ([]byte, error) {
+	result, err := json.Marshal(problemMembers{
+		Type:     p.Type,
+		Title:    p.Title,
+		Status:   p.Status,
+		Detail:   p.Detail,
+		Instance: p.Instance,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	names := p.extensionNames()
+	if len(names) == 0 {
+		return result, nil
+	}
+
+	buf := bytes.NewBuffer(result[:len(result)-1])
+	for i, name := range names {
+		if i > 0 || len(result) > 2 {
+			buf.WriteByte(',')
+		}
+
+		key, _ := json.Marshal(name)
+		value, err := json.Marshal(p.Extensions[name])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// MarshalXML encodes the problem as a problem element in the RFC 7807
+// namespace, with an element for every extension member.
+func (p Problem) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start = xml.StartElement{Name: xml.Name{Space: problemNamespace, Local: "problem"}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	members := []struct {
+		name  string
+		value interface{}
+		empty bool
+	}{
+		{"type", p.Type, len(p.Type) == 0},
+		{"title", p.Title, len(p.Title) == 0},
+		{"status", p.Status, p.Status == 0},
+		{"detail", p.Detail, len(p.Detail) == 0},
+		{"instance", p.Instance, len(p.Instance) == 0},
+	}
+	for _, m := range members {
+		if m.empty {
+			continue
+		}
+		if err := e.EncodeElement(m.value, xml.StartElement{Name: xml.Name{Local: m.name}}); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range p.extensionNames() {
+		if err := e.EncodeElement(p.Extensions[name], xml.StartElement{Name: xml.Name{Local: name}}); err != nil {
+			return err
+		}
+	}
+
+	return e.EncodeToken(start.End())
+}
+
+// prepareProblem fills in the status and title of a problem if they are missing.
+func prepareProblem(p Problem) Problem {
+	if p.Status == 0 {
+		p.Status = http.StatusInternalServerError
+	}
+	if len(p.Title) == 0 && (len(p.Type) == 0 || p.Type == "about:blank") {
+		p.Title = http.StatusText(p.Status)
+	}
+	return p
+}
+
+// Problem writes out an RFC 7807 problem details document as JSON. The
+// response status is taken from the problem, defaulting to 500.
+func (r *Render) Problem(w io.Writer, p Problem) error {
+	p = prepareProblem(p)
+
+	head := Head{
+		ContentType: ContentProblemJSON + r.compiledCharset,
+		Status:      p.Status,
+		Headers:     p.Headers,
+		Cookies:     p.Cookies,
+	}
+
+	j := JSON{
+		Head:   head,
+		Indent: r.opt.IndentJSON,
+	}
+
+	return r.Render(w, j, p)
+}
+
+// ProblemXML writes out an RFC 7807 problem details document as XML. The
+// response status is taken from the problem, defaulting to 500.
+func (r *Render) ProblemXML(w io.Writer, p Problem) error {
+	p = prepareProblem(p)
+
+	head := Head{
+		ContentType: ContentProblemXML + r.compiledCharset,
+		Status:      p.Status,
+		Headers:     p.Headers,
+		Cookies:     p.Cookies,
+	}
+
+	x := XML{
+		Head:   head,
+		Indent: r.opt.IndentXML,
+		Prefix: r.opt.PrefixXML,
+	}
+
+	return r.Render(w, x, p)
+}
+
+// writeProblem writes err out as a problem details document. The error
+// message is only exposed in development mode.
+func (r *Render) writeProblem(hw http.ResponseWriter, err error, status int) {
+	p := prepareProblem(Problem{Status: status})
+	if r.opt.IsDevelopment {
+		p.Detail = err.Error()
+	}
+
+	j := JSON{
+		Head: Head{
+			ContentType: ContentProblemJSON + r.compiledCharset,
+			Status:      status,
+		},
+	}
+
+	h := hw.Header()
+	h.Del(ContentLength)
+	h.Set("X-Content-Type-Options", "nosniff")
+	if jerr := j.Render(hw, p); jerr != nil {
+		http.Error(hw, http.StatusText(status), status)
+	}
+}
@@ -0,0 +1,147 @@
+package render
+
+import (
+	"encoding"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+)
+
+// ContentProtobuf header value for Protocol Buffers data.
+const ContentProtobuf = "application/x-protobuf"
+
+// ProtoMarshaler is implemented by messages that marshal themselves to the
+// protobuf wire format, such as the ones generated by gogo/protobuf.
+type ProtoMarshaler interface {
+	Marshal() ([]byte, error)
+}
+
+// Protobuf built-in renderer. It renders a message in its binary encoding, or
+// as its canonical JSON mapping if MarshalJSON is set.
+type Protobuf struct {
+	Head
+	// Marshal returns the binary encoding of a message, e.g. with
+	// proto.Marshal. If it is nil, ProtoMarshaler and
+	// encoding.BinaryMarshaler are used.
+	Marshal func(v interface{}) ([]byte, error)
+	// MarshalJSON returns the canonical JSON mapping of a message, e.g. with
+	// protojson. The binary encoding is rendered if it is nil.
+	MarshalJSON func(v interface{}) ([]byte, error)
+}
+
+// Render a protobuf response.
+func (p Protobuf) Render(w io.Writer, v interface{}) error {
+	var result []byte
+	var err error
+	switch {
+	case p.MarshalJSON != nil:
+		result, err = p.MarshalJSON(v)
+	case p.Marshal != nil:
+		result, err = p.Marshal(v)
+	default:
+		result, err = marshalProto(v)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Protobuf marshaled fine, write out the result.
+	if hw, ok := w.(http.ResponseWriter); ok {
+		p.Head.Write(hw)
+	}
+	w.Write(result)
+	return nil
+}
+
+// marshalProto returns the binary encoding of a message.
+func marshalProto(v interface{}) ([]byte, error) {
+	switch m := v.(type) {
+	case ProtoMarshaler:
+		return m.Marshal()
+	case encoding.BinaryMarshaler:
+		return m.MarshalBinary()
+	}
+	return nil, fmt.Errorf("render: %T does not implement ProtoMarshaler or encoding.BinaryMarshaler", v)
+}
+
+// isProto reports whether v can be rendered as a protobuf message. Messages
+// generated by google.golang.org/protobuf only have a ProtoReflect method, and
+// are rendered through Options.ProtobufMarshal.
+func (r *Render) isProto(v interface{}) bool {
+	switch v.(type) {
+	case ProtoMarshaler, encoding.BinaryMarshaler:
+		return true
+	}
+	if r.opt.ProtobufMarshal == nil || v == nil {
+		return false
+	}
+	_, ok := reflect.TypeOf(v).MethodByName("ProtoReflect")
+	return ok
+}
+
+// Protobuf marshals the given message and writes the protobuf response.
+func (r *Render) Protobuf(w io.Writer, status int, v interface{}) error {
+	return r.protobuf(w, status, v, RequestOptions{})
+}
+
+// ProtobufRequest is like Protobuf, but does not render once the request's
+// context is done.
+func (r *Render) ProtobufRequest(w io.Writer, req *http.Request, status int, v interface{}, reqOpt ...RequestOptions) error {
+	opt := prepareRequestOptions(reqOpt)
+	return r.serve(w, req, false, false, opt, func(w io.Writer) error {
+		return r.protobuf(w, status, v, opt)
+	})
+}
+
+func (r *Render) protobuf(w io.Writer, status int, v interface{}, opt RequestOptions) error {
+	head := Head{
+		ContentType: r.opt.ProtobufContentType,
+		Status:      status,
+		Headers:     opt.Headers,
+		Cookies:     opt.Cookies,
+	}
+
+	p := Protobuf{
+		Head:    head,
+		Marshal: r.opt.ProtobufMarshal,
+	}
+
+	return r.Render(w, p, v)
+}
+
+// ProtobufJSON writes the canonical JSON mapping of the given message, as
+// returned by Options.ProtobufJSON. Without it, the message is rendered like
+// JSON does.
+func (r *Render) ProtobufJSON(w io.Writer, status int, v interface{}) error {
+	return r.protobufJSON(w, status, v, RequestOptions{})
+}
+
+// ProtobufJSONRequest is like ProtobufJSON, but does not render once the
+// request's context is done.
+func (r *Render) ProtobufJSONRequest(w io.Writer, req *http.Request, status int, v interface{}, reqOpt ...RequestOptions) error {
+	opt := prepareRequestOptions(reqOpt)
+	return r.serve(w, req, false, false, opt, func(w io.Writer) error {
+		return r.protobufJSON(w, status, v, opt)
+	})
+}
+
+func (r *Render) protobufJSON(w io.Writer, status int, v interface{}, opt RequestOptions) error {
+	if r.opt.ProtobufJSON == nil {
+		return r.json(w, status, v, opt)
+	}
+
+	head := Head{
+		ContentType: r.opt.JSONContentType + r.compiledCharset,
+		Status:      status,
+		Headers:     opt.Headers,
+		Cookies:     opt.Cookies,
+	}
+
+	p := Protobuf{
+		Head:        head,
+		MarshalJSON: r.opt.ProtobufJSON,
+	}
+
+	return r.Render(w, p, v)
+}
@@ -1,27 +1,31 @@
 package render
 
 import (
-	"bytes"
-	"fmt"
+	"errors"
 	"html/template"
 	"io"
+	"io/fs"
 	"log"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 const (
 	// ContentBinary header value for binary data.
 	ContentBinary = "application/octet-stream"
+	// ContentCBOR header value for CBOR data.
+	ContentCBOR = "application/cbor"
 	// ContentHTML header value for HTML data.
 	ContentHTML = "text/html"
 	// ContentJSON header value for JSON data.
 	ContentJSON = "application/json"
 	// ContentJSONP header value for JSONP data.
 	ContentJSONP = "application/javascript"
+	// ContentMsgPack header value for MessagePack data.
+	ContentMsgPack = "application/msgpack"
 	// ContentLength header constant.
 	ContentLength = "Content-Length"
 	// ContentText header value for Text data.
@@ -32,6 +36,8 @@ const (
 	ContentXHTML = "application/xhtml+xml"
 	// ContentXML header value for XML data.
 	ContentXML = "text/xml"
+	// ContentYAML header value for YAML data.
+	ContentYAML = "application/yaml"
 	// Default character encoding.
 	defaultCharset = "UTF-8"
 )
@@ -48,15 +54,19 @@ type Delims struct {
 
 // Options is a struct for specifying configuration options for the render.Render object.
 type Options struct {
-	// Directory to load templates. Default is "templates".
+	// Directory to load templates. Default is "templates", or the root of FS if it is set.
 	Directory string
+	// FS to load templates from, e.g. an embed.FS. Takes precedence over Asset and FileSystem. Defaults to nil.
+	FS fs.FS
 	// FileSystem to access files
 	FileSystem FileSystem
+	// FileDirectory that File serves files from, within FS or FileSystem. Paths that escape it are answered with 404 Not Found. Default is "", which serves no files.
+	FileDirectory string
 	// Asset function to use in place of directory. Defaults to nil.
 	Asset func(name string) ([]byte, error)
 	// AssetNames function to use in place of directory. Defaults to nil.
 	AssetNames func() []string
-	// Layout template name. Will not render a layout if blank (""). Defaults to blank ("").
+	// Layout template name. Will not render a layout if blank (""). A layout can declare a parent layout with {{ layout "name" }}. Defaults to blank ("").
 	Layout string
 	// Extensions to parse template files from. Defaults to [".tmpl"].
 	Extensions []string
@@ -72,34 +82,86 @@ type Options struct {
 	IndentJSON bool
 	// Outputs human readable XML. Default is false.
 	IndentXML bool
+	// Number of spaces nested YAML blocks are indented by. Default is 2.
+	IndentYAML int
 	// Prefixes the JSON output with the given bytes. Default is false.
 	PrefixJSON []byte
 	// Prefixes the XML output with the given bytes.
 	PrefixXML []byte
 	// Allows changing the binary content type.
 	BinaryContentType string
+	// Allows changing the CBOR content type.
+	CBORContentType string
+	// Allows changing the CSV content type.
+	CSVContentType string
+	// Allows changing the server-sent events content type.
+	EventStreamContentType string
 	// Allows changing the HTML content type.
 	HTMLContentType string
 	// Allows changing the JSON content type.
 	JSONContentType string
 	// Allows changing the JSONP content type.
 	JSONPContentType string
+	// Allows changing the MessagePack content type.
+	MsgPackContentType string
+	// Allows changing the NDJSON content type.
+	NDJSONContentType string
+	// Allows changing the protobuf content type.
+	ProtobufContentType string
 	// Allows changing the Text content type.
 	TextContentType string
+	// Allows changing the TSV content type.
+	TSVContentType string
 	// Allows changing the XML content type.
 	XMLContentType string
-	// If IsDevelopment is set to true, this will recompile the templates on every request. Default is false.
+	// Allows changing the YAML content type.
+	YAMLContentType string
+	// If IsDevelopment is set to true, this will recompile the templates on every request, unless WatchTemplates is set. Default is false.
 	IsDevelopment bool
+	// If WatchTemplates is set to true, the templates are polled for changes in the background and only the changed ones are recompiled. Default is false.
+	WatchTemplates bool
+	// Interval at which templates are polled for changes when WatchTemplates is set. Default is one second.
+	WatchInterval time.Duration
+	// OnReload is called after templates changed and were reloaded, see Render.Reload. Defaults to nil.
+	OnReload func(ReloadEvent)
 	// Unescape HTML characters "&<>" to their original values. Default is false.
 	UnEscapeHTML bool
+	// ProtobufMarshal returns the binary encoding of a protobuf message, used by Protobuf and for messages negotiated as protobuf. Defaults to nil, which uses the Marshal or MarshalBinary method of the message.
+	ProtobufMarshal func(v interface{}) ([]byte, error)
+	// ProtobufJSON returns the canonical JSON mapping of a protobuf message, used by ProtobufJSON and for messages negotiated as JSON. Defaults to nil, which renders them like JSON.
+	ProtobufJSON func(v interface{}) ([]byte, error)
 	// Streams JSON responses instead of marshalling prior to sending. Default is false.
 	StreamingJSON bool
+	// Streams HTML responses: the layout is written as it executes and flushed right before it yields to the page. Default is false.
+	StreamingHTML bool
+	// Interval of the keep-alive comments sent on idle server-sent event streams. Default is 15 seconds, a negative value disables them.
+	SSEKeepAlive time.Duration
 	// Require that all partials executed in the layout are implemented in all templates using the layout. Default is false.
 	RequirePartials bool
+	// Checks the component calls of all templates against the props and slots the components declare when the templates are compiled, as far as their names are constant. Calls are always checked when they are executed. Default is false.
+	StrictComponents bool
 	// Deprecated: Use the above `RequirePartials` instead of this. As of Go 1.6, blocks are built in. Default is false.
 	RequireBlocks bool
+	// RequestFuncs returns template funcs for a single request, used by HTMLRequest. As templates are parsed upfront, the funcs must also be declared in Funcs. Defaults to nil.
+	RequestFuncs func(req *http.Request) template.FuncMap
+	// RequestData returns default data for a single request, used by HTMLRequest. It is merged into nil and map[string]interface{} bindings, and available to all templates through the requestData func. Defaults to nil.
+	RequestData func(req *http.Request) map[string]interface{}
+	// Compresses the responses of the request-aware render calls, such as HTMLRequest, with gzip or deflate as accepted by the client. Default is false.
+	Compression bool
+	// Responses smaller than CompressionMinSize bytes are not compressed. Default is 1024.
+	CompressionMinSize int
+	// Content types that are compressed. Defaults to the text based types of the built-in engines, plus CSS, CSV, JavaScript and SVG.
+	CompressionTypes []string
+	// Headers are added to every response, unless they are already set on it or by the Engine. Defaults to nil.
+	Headers http.Header
+	// ETag adds an ETag computed from the body to the responses of the request-aware render calls, and answers matching conditional requests with 304 Not Modified. Streamed responses are excluded. Default is ETagDefault, which disables it.
+	ETag ETagMode
 	// Disables automatic rendering of http.StatusInternalServerError when an error occurs. Default is false.
 	DisableHTTPErrorRendering bool
+	// ErrorHandler is called when an Engine fails to render, instead of the automatic error rendering. Defaults to nil.
+	ErrorHandler ErrorHandler
+	// Renders errors as RFC 7807 application/problem+json documents instead of plain text. The error message is only included if IsDevelopment is set. Default is false.
+	ProblemDetails bool
 	// Enables using partials without the current filename suffix which allows use of the same template in multiple files. e.g {{ partial "carosuel" }} inside the home template will match carosel-home or carosel.
 	// ***NOTE*** - This option should be named RenderPartialsWithoutSuffix as that is what it does. "Prefix" is a typo. Maintaining the existing name for backwards compatibility.
 	RenderPartialsWithoutPrefix bool
@@ -115,6 +177,15 @@ type HTMLOptions struct {
 	Layout string
 	// Funcs added to Options.Funcs.
 	Funcs template.FuncMap
+	// Streaming streams this response, see Options.StreamingHTML.
+	Streaming bool
+	// ETag overrides Options.ETag, for HTMLRequest.
+	ETag ETagMode
+	// LastModified of the response, for HTMLRequest. See RequestOptions.
+	LastModified time.Time
+	// Headers and Cookies sent with the response, see Head.
+	Headers http.Header
+	Cookies []*http.Cookie
 }
 
 // Render is a service that provides functions for easily writing JSON, XML,
@@ -122,13 +193,29 @@ type HTMLOptions struct {
 type Render struct {
 	// Customize Secure with an Options struct.
 	opt             Options
-	templates       *template.Template
-	templatesLk     sync.Mutex
+	templates       atomic.Value
 	compiledCharset string
+	negotiators     []negotiator
+
+	reloadLk     sync.Mutex
+	scanned      map[string]templateFile
+	stopWatching chan struct{}
+	closeOnce    sync.Once
 }
 
-// New constructs a new Render instance with the supplied options.
+// New constructs a new Render instance with the supplied options. It panics
+// if any of the templates fail to compile, see NewWithError.
 func New(options ...Options) *Render {
+	r, err := NewWithError(options...)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// NewWithError constructs a new Render instance with the supplied options. If
+// any of the templates fail to compile a *CompileError is returned.
+func NewWithError(options ...Options) (*Render, error) {
 	var o Options
 	if len(options) > 0 {
 		o = options[0]
@@ -139,9 +226,17 @@ func New(options ...Options) *Render {
 	}
 
 	r.prepareOptions()
-	r.compileTemplates()
+	if err := r.Recompile(); err != nil {
+		return nil, err
+	}
+	r.registerBuiltinEngines()
 
-	return &r
+	if r.opt.WatchTemplates {
+		r.stopWatching = make(chan struct{})
+		go r.watchTemplates()
+	}
+
+	return &r, nil
 }
 
 func (r *Render) prepareOptions() {
@@ -154,7 +249,11 @@ func (r *Render) prepareOptions() {
 	}
 
 	if len(r.opt.Directory) == 0 {
-		r.opt.Directory = "templates"
+		if r.opt.FS != nil {
+			r.opt.Directory = "."
+		} else {
+			r.opt.Directory = "templates"
+		}
 	}
 	if r.opt.FileSystem == nil {
 		r.opt.FileSystem = &LocalFileSystem{}
@@ -165,6 +264,15 @@ func (r *Render) prepareOptions() {
 	if len(r.opt.BinaryContentType) == 0 {
 		r.opt.BinaryContentType = ContentBinary
 	}
+	if len(r.opt.CBORContentType) == 0 {
+		r.opt.CBORContentType = ContentCBOR
+	}
+	if len(r.opt.CSVContentType) == 0 {
+		r.opt.CSVContentType = ContentCSV
+	}
+	if len(r.opt.EventStreamContentType) == 0 {
+		r.opt.EventStreamContentType = ContentEventStream
+	}
 	if len(r.opt.HTMLContentType) == 0 {
 		r.opt.HTMLContentType = ContentHTML
 	}
@@ -174,210 +282,249 @@ func (r *Render) prepareOptions() {
 	if len(r.opt.JSONPContentType) == 0 {
 		r.opt.JSONPContentType = ContentJSONP
 	}
+	if len(r.opt.MsgPackContentType) == 0 {
+		r.opt.MsgPackContentType = ContentMsgPack
+	}
+	if len(r.opt.NDJSONContentType) == 0 {
+		r.opt.NDJSONContentType = ContentNDJSON
+	}
+	if len(r.opt.ProtobufContentType) == 0 {
+		r.opt.ProtobufContentType = ContentProtobuf
+	}
 	if len(r.opt.TextContentType) == 0 {
 		r.opt.TextContentType = ContentText
 	}
+	if len(r.opt.TSVContentType) == 0 {
+		r.opt.TSVContentType = ContentTSV
+	}
 	if len(r.opt.XMLContentType) == 0 {
 		r.opt.XMLContentType = ContentXML
 	}
+	if len(r.opt.YAMLContentType) == 0 {
+		r.opt.YAMLContentType = ContentYAML
+	}
+	if r.opt.IndentYAML <= 0 {
+		r.opt.IndentYAML = 2
+	}
+	if r.opt.SSEKeepAlive == 0 {
+		r.opt.SSEKeepAlive = defaultSSEKeepAlive
+	}
+	if r.opt.WatchInterval <= 0 {
+		r.opt.WatchInterval = time.Second
+	}
+	if r.opt.CompressionMinSize <= 0 {
+		r.opt.CompressionMinSize = defaultCompressionMinSize
+	}
+	if len(r.opt.CompressionTypes) == 0 {
+		r.opt.CompressionTypes = defaultCompressionTypes
+	}
 	if r.opt.BufferPool == nil {
 		// 32 buffers of size 512KiB each
 		r.opt.BufferPool = NewSizedBufferPool(32, 1<<19)
 	}
 }
 
-func (r *Render) compileTemplates() {
-	if r.opt.Asset == nil || r.opt.AssetNames == nil {
-		r.compileTemplatesFromDir()
-		return
+// Recompile compiles the templates again. If any of them fail to compile a
+// *CompileError is returned and the previously compiled templates are kept.
+func (r *Render) Recompile() error {
+	templates, files, err := r.compileTemplates()
+	if err != nil {
+		return err
+	}
+	return r.setTemplates(templates, files)
+}
+
+// setTemplates atomically swaps in a newly compiled set of templates.
+func (r *Render) setTemplates(templates *template.Template, files map[string]templateFile) error {
+	set, err := newTemplateSet(&r.opt, templates, files)
+	if err != nil {
+		return err
 	}
-	r.compileTemplatesFromAsset()
+	r.templates.Store(set)
+	return nil
 }
 
-func (r *Render) compileTemplatesFromDir() {
-	dir := r.opt.Directory
-	r.templates = template.New(dir)
-	r.templates.Delims(r.opt.Delims.Left, r.opt.Delims.Right)
+func (r *Render) templateSet() *templateSet {
+	return r.templates.Load().(*templateSet)
+}
 
-	// Walk the supplied directory and compile any files that match our extension list.
-	r.opt.FileSystem.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		// Fix same-extension-dirs bug: some dir might be named to: "users.tmpl", "local.html".
-		// These dirs should be excluded as they are not valid golang templates, but files under
-		// them should be treat as normal.
-		// If is a dir, return immediately (dir is not a valid golang template).
-		if info == nil || info.IsDir() {
+func (r *Render) compileTemplates() (*template.Template, map[string]templateFile, error) {
+	templates := template.New(r.opt.Directory)
+	templates.Delims(r.opt.Delims.Left, r.opt.Delims.Right)
+	files := map[string]templateFile{}
+	errs := &CompileError{}
+	source := r.templateSource()
+
+	// Walk the template source and compile any files that match our extension list.
+	err := source.walk(func(path, rel string, info fs.FileInfo) error {
+		ext := r.templateExtension(rel)
+		if ext == "" {
 			return nil
 		}
 
-		rel, err := filepath.Rel(dir, path)
+		buf, err := source.readFile(path)
 		if err != nil {
-			return err
+			errs.add(path, err)
+			return nil
 		}
 
-		ext := ""
-		if strings.Index(rel, ".") != -1 {
-			ext = filepath.Ext(rel)
+		file := templateFile{
+			name:  rel[0 : len(rel)-len(ext)],
+			stamp: fileStamp(info, buf),
 		}
+		files[path] = file
 
-		for _, extension := range r.opt.Extensions {
-			if ext == extension {
-				buf, err := r.opt.FileSystem.ReadFile(path)
-				if err != nil {
-					panic(err)
-				}
-
-				name := (rel[0 : len(rel)-len(ext)])
-				tmpl := r.templates.New(filepath.ToSlash(name))
-
-				// Add our funcmaps.
-				for _, funcs := range r.opt.Funcs {
-					tmpl.Funcs(funcs)
-				}
-
-				// Break out if this parsing fails. We don't want any silent server starts.
-				template.Must(tmpl.Funcs(helperFuncs).Parse(string(buf)))
-				break
-			}
+		if err := r.parseTemplate(templates, file.name, buf); err != nil {
+			errs.add(path, err)
 		}
 		return nil
 	})
-}
-
-func (r *Render) compileTemplatesFromAsset() {
-	dir := r.opt.Directory
-	r.templates = template.New(dir)
-	r.templates.Delims(r.opt.Delims.Left, r.opt.Delims.Right)
-
-	for _, path := range r.opt.AssetNames() {
-		if !strings.HasPrefix(path, dir) {
-			continue
-		}
+	if err != nil {
+		errs.add(r.opt.Directory, err)
+	}
+	checkLayouts(&r.opt, templates, files, errs)
+	if r.opt.StrictComponents {
+		checkComponents(&r.opt, templates, files, errs)
+	}
 
-		rel, err := filepath.Rel(dir, path)
-		if err != nil {
-			panic(err)
-		}
+	return templates, files, errs.orNil()
+}
 
-		ext := ""
-		if strings.Index(rel, ".") != -1 {
-			ext = "." + strings.Join(strings.Split(rel, ".")[1:], ".")
+// templateExtension returns the longest of the configured extensions that
+// the given file name ends with, or "" if there is none. This allows for
+// multi-part extensions such as ".html.tmpl".
+func (r *Render) templateExtension(name string) string {
+	ext := ""
+	for _, extension := range r.opt.Extensions {
+		if len(extension) > len(ext) && len(name) > len(extension) && strings.HasSuffix(name, extension) {
+			ext = extension
 		}
+	}
+	return ext
+}
 
-		for _, extension := range r.opt.Extensions {
-			if ext == extension {
-
-				buf, err := r.opt.Asset(path)
-				if err != nil {
-					panic(err)
-				}
-
-				name := (rel[0 : len(rel)-len(ext)])
-				tmpl := r.templates.New(filepath.ToSlash(name))
-
-				// Add our funcmaps.
-				for _, funcs := range r.opt.Funcs {
-					tmpl.Funcs(funcs)
-				}
+// parseTemplate adds the template with the given name and content to templates.
+func (r *Render) parseTemplate(templates *template.Template, name string, buf []byte) error {
+	tmpl := templates.New(name)
 
-				// Break out if this parsing fails. We don't want any silent server starts.
-				template.Must(tmpl.Funcs(helperFuncs).Parse(string(buf)))
-				break
-			}
-		}
+	// Add our funcmaps.
+	if _, err := tmpl.Funcs(templateFuncs(&r.opt)).Parse(string(buf)); err != nil {
+		return err
 	}
+	return addSections(templates, name, buf)
 }
 
 // TemplateLookup is a wrapper around template.Lookup and returns
 // the template with the given name that is associated with t, or nil
 // if there is no such template.
 func (r *Render) TemplateLookup(t string) *template.Template {
-	return r.templates.Lookup(t)
-}
-
-func (r *Render) execute(name string, binding interface{}) (*bytes.Buffer, error) {
-	buf := new(bytes.Buffer)
-	return buf, r.templates.ExecuteTemplate(buf, name, binding)
-}
-
-func (r *Render) layoutFuncs(name string, binding interface{}) template.FuncMap {
-	return template.FuncMap{
-		"yield": func() (template.HTML, error) {
-			buf, err := r.execute(name, binding)
-			// Return safe HTML here since we are rendering our own template.
-			return template.HTML(buf.String()), err
-		},
-		"current": func() (string, error) {
-			return name, nil
-		},
-		"block": func(partialName string) (template.HTML, error) {
-			log.Print("Render's `block` implementation is now depericated. Use `partial` as a drop in replacement.")
-			fullPartialName := fmt.Sprintf("%s-%s", partialName, name)
-			if r.TemplateLookup(fullPartialName) == nil && r.opt.RenderPartialsWithoutPrefix {
-				fullPartialName = partialName
-			}
-			if r.opt.RequireBlocks || r.TemplateLookup(fullPartialName) != nil {
-				buf, err := r.execute(fullPartialName, binding)
-				// Return safe HTML here since we are rendering our own template.
-				return template.HTML(buf.String()), err
-			}
-			return "", nil
-		},
-		"partial": func(partialName string) (template.HTML, error) {
-			fullPartialName := fmt.Sprintf("%s-%s", partialName, name)
-			if r.TemplateLookup(fullPartialName) == nil && r.opt.RenderPartialsWithoutPrefix {
-				fullPartialName = partialName
-			}
-			if r.opt.RequirePartials || r.TemplateLookup(fullPartialName) != nil {
-				buf, err := r.execute(fullPartialName, binding)
-				// Return safe HTML here since we are rendering our own template.
-				return template.HTML(buf.String()), err
-			}
-			return "", nil
-		},
-	}
+	return r.templateSet().lookup.Lookup(t)
 }
 
+// prepareHTMLOptions merges the HTMLOptions of a single call with the
+// defaults. The returned Funcs only hold the funcs added by that call, as
+// Options.Funcs are already part of the compiled templates.
 func (r *Render) prepareHTMLOptions(htmlOpt []HTMLOptions) HTMLOptions {
 	layout := r.opt.Layout
-	funcs := template.FuncMap{}
-
-	for _, tmp := range r.opt.Funcs {
-		for k, v := range tmp {
-			funcs[k] = v
-		}
-	}
+	streaming := r.opt.StreamingHTML
+	var funcs template.FuncMap
+	var etag ETagMode
+	var lastModified time.Time
+	var headers http.Header
+	var cookies []*http.Cookie
 
 	if len(htmlOpt) > 0 {
 		opt := htmlOpt[0]
 		if len(opt.Layout) > 0 {
 			layout = opt.Layout
 		}
-
-		for k, v := range opt.Funcs {
-			funcs[k] = v
-		}
+		funcs = opt.Funcs
+		streaming = streaming || opt.Streaming
+		etag = opt.ETag
+		lastModified = opt.LastModified
+		headers = opt.Headers
+		cookies = opt.Cookies
 	}
 
 	return HTMLOptions{
-		Layout: layout,
-		Funcs:  funcs,
+		Layout:       layout,
+		Funcs:        funcs,
+		Streaming:    streaming,
+		ETag:         etag,
+		LastModified: lastModified,
+		Headers:      headers,
+		Cookies:      cookies,
 	}
 }
 
 // Render is the generic function called by XML, JSON, Data, HTML, and can be called by custom implementations.
 func (r *Render) Render(w io.Writer, e Engine, data interface{}) error {
-	err := e.Render(w, data)
-	if hw, ok := w.(http.ResponseWriter); err != nil && !r.opt.DisableHTTPErrorRendering && ok {
-		http.Error(hw, err.Error(), http.StatusInternalServerError)
+	if r.opt.ErrorHandler != nil && !inErrorHandler(w) {
+		return r.renderWithErrorHandler(w, e, data)
+	}
+
+	err := e.Render(r.withDefaultHeaders(w), data)
+	if err != nil {
+		r.renderError(w, err, http.StatusInternalServerError)
 	}
 	return err
 }
 
+// renderWithErrorHandler renders while keeping track of the response headers,
+// and passes any error on to Options.ErrorHandler.
+func (r *Render) renderWithErrorHandler(w io.Writer, e Engine, data interface{}) error {
+	var tracker *headerTracker
+	tw := r.withDefaultHeaders(w)
+	if hw, ok := tw.(http.ResponseWriter); ok {
+		tracker = &headerTracker{ResponseWriter: hw}
+		tw = tracker
+	}
+
+	err := e.Render(tw, data)
+	if err == nil {
+		return nil
+	}
+
+	return r.handleError(w, &RenderError{
+		Engine:         e,
+		Data:           data,
+		Err:            err,
+		Status:         http.StatusInternalServerError,
+		HeadersWritten: tracker != nil && tracker.wroteHeader,
+	})
+}
+
+// renderError writes err out with the given status unless DisableHTTPErrorRendering is set.
+// Streams that failed midway are left as they are.
+func (r *Render) renderError(w io.Writer, err error, status int) {
+	hw, ok := w.(http.ResponseWriter)
+	if !ok || r.opt.DisableHTTPErrorRendering {
+		return
+	}
+
+	var streamErr *StreamError
+	if errors.As(err, &streamErr) {
+		return
+	}
+
+	if r.opt.ProblemDetails {
+		r.writeProblem(hw, err, status)
+		return
+	}
+	http.Error(hw, err.Error(), status)
+}
+
 // Data writes out the raw bytes as binary data.
 func (r *Render) Data(w io.Writer, status int, v []byte) error {
+	return r.data(w, status, v, RequestOptions{})
+}
+
+func (r *Render) data(w io.Writer, status int, v []byte, opt RequestOptions) error {
 	head := Head{
 		ContentType: r.opt.BinaryContentType,
 		Status:      status,
+		Headers:     opt.Headers,
+		Cookies:     opt.Cookies,
 	}
 
 	d := Data{
@@ -389,46 +536,83 @@ func (r *Render) Data(w io.Writer, status int, v []byte) error {
 
 // HTML builds up the response from the specified template and bindings.
 func (r *Render) HTML(w io.Writer, status int, name string, binding interface{}, htmlOpt ...HTMLOptions) error {
-	r.templatesLk.Lock()
-	defer r.templatesLk.Unlock()
+	return r.html(w, nil, status, name, "", binding, htmlOpt)
+}
 
+// html renders an HTML response, for the given request if it is not nil. If
+// a fragment is given, only that block of the page is rendered.
+func (r *Render) html(w io.Writer, req *http.Request, status int, name, fragment string, binding interface{}, htmlOpt []HTMLOptions) error {
 	// If we are in development mode, recompile the templates on every HTML request.
-	if r.opt.IsDevelopment {
-		r.compileTemplates()
+	// Templates that fail to compile are reported and the previous ones are kept.
+	if r.opt.IsDevelopment && !r.opt.WatchTemplates {
+		if err := r.Recompile(); err != nil {
+			log.Print(err)
+		}
 	}
 
-	opt := r.prepareHTMLOptions(htmlOpt)
-	if tpl := r.templates.Lookup(name); tpl != nil {
-		if len(opt.Layout) > 0 {
-			tpl.Funcs(r.layoutFuncs(name, binding))
-			name = opt.Layout
-		}
+	c, err := r.templateSet().get()
+	if err != nil {
+		return r.failRender(w, nil, binding, err, http.StatusInternalServerError)
+	}
+	defer c.release()
 
-		if len(opt.Funcs) > 0 {
-			tpl.Funcs(opt.Funcs)
+	opt := r.prepareHTMLOptions(htmlOpt)
+	state := renderState{
+		name:     name,
+		binding:  binding,
+		layout:   c.tpl.Lookup(name) != nil && len(opt.Layout) > 0 && len(fragment) == 0,
+		fragment: len(fragment) > 0,
+		funcs:    opt.Funcs,
+	}
+	if len(fragment) > 0 {
+		if name, err = c.lookupFragment(name, fragment); err != nil {
+			return r.failRender(w, nil, binding, err, http.StatusInternalServerError)
 		}
 	}
+	if req != nil {
+		r.prepareRequestState(&state, req)
+	}
+	if state.layout {
+		state.layouts = c.set.layoutChain(opt.Layout)
+		name = state.layouts[0]
+		state.deferred = newDeferredPartials()
+	}
+	if opt.Streaming {
+		state.stream = &htmlStream{}
+	}
+	c.bind(state)
 
 	head := Head{
 		ContentType: r.opt.HTMLContentType + r.compiledCharset,
 		Status:      status,
+		Headers:     opt.Headers,
+		Cookies:     opt.Cookies,
 	}
 
 	h := HTML{
 		Head:      head,
 		Name:      name,
-		Templates: r.templates,
+		Templates: c.tpl,
+		Streaming: opt.Streaming,
 		bp:        r.opt.BufferPool,
+		stream:    state.stream,
+		deferred:  state.deferred,
 	}
 
-	return r.Render(w, h, binding)
+	return r.Render(w, h, state.binding)
 }
 
 // JSON marshals the given interface object and writes the JSON response.
 func (r *Render) JSON(w io.Writer, status int, v interface{}) error {
+	return r.json(w, status, v, RequestOptions{})
+}
+
+func (r *Render) json(w io.Writer, status int, v interface{}, opt RequestOptions) error {
 	head := Head{
 		ContentType: r.opt.JSONContentType + r.compiledCharset,
 		Status:      status,
+		Headers:     opt.Headers,
+		Cookies:     opt.Cookies,
 	}
 
 	j := JSON{
@@ -444,9 +628,15 @@ func (r *Render) JSON(w io.Writer, status int, v interface{}) error {
 
 // JSONP marshals the given interface object and writes the JSON response.
 func (r *Render) JSONP(w io.Writer, status int, callback string, v interface{}) error {
+	return r.jsonp(w, status, callback, v, RequestOptions{})
+}
+
+func (r *Render) jsonp(w io.Writer, status int, callback string, v interface{}, opt RequestOptions) error {
 	head := Head{
 		ContentType: r.opt.JSONPContentType + r.compiledCharset,
 		Status:      status,
+		Headers:     opt.Headers,
+		Cookies:     opt.Cookies,
 	}
 
 	j := JSONP{
@@ -460,9 +650,15 @@ func (r *Render) JSONP(w io.Writer, status int, callback string, v interface{})
 
 // Text writes out a string as plain text.
 func (r *Render) Text(w io.Writer, status int, v string) error {
+	return r.text(w, status, v, RequestOptions{})
+}
+
+func (r *Render) text(w io.Writer, status int, v string, opt RequestOptions) error {
 	head := Head{
 		ContentType: r.opt.TextContentType + r.compiledCharset,
 		Status:      status,
+		Headers:     opt.Headers,
+		Cookies:     opt.Cookies,
 	}
 
 	t := Text{
@@ -474,9 +670,20 @@ func (r *Render) Text(w io.Writer, status int, v string) error {
 
 // XML marshals the given interface object and writes the XML response.
 func (r *Render) XML(w io.Writer, status int, v interface{}) error {
+	return r.xml(w, status, v, RequestOptions{})
+}
+
+func (r *Render) xml(w io.Writer, status int, v interface{}, opt RequestOptions) error {
+	return r.xmlAs(w, r.opt.XMLContentType, status, v, opt)
+}
+
+// xmlAs renders XML with the given content type.
+func (r *Render) xmlAs(w io.Writer, contentType string, status int, v interface{}, opt RequestOptions) error {
 	head := Head{
-		ContentType: r.opt.XMLContentType + r.compiledCharset,
+		ContentType: contentType + r.compiledCharset,
 		Status:      status,
+		Headers:     opt.Headers,
+		Cookies:     opt.Cookies,
 	}
 
 	x := XML{
@@ -487,3 +694,116 @@ func (r *Render) XML(w io.Writer, status int, v interface{}) error {
 
 	return r.Render(w, x, v)
 }
+
+// MsgPack marshals the given interface object and writes the MessagePack response.
+func (r *Render) MsgPack(w io.Writer, status int, v interface{}) error {
+	return r.msgPack(w, status, v, RequestOptions{})
+}
+
+func (r *Render) msgPack(w io.Writer, status int, v interface{}, opt RequestOptions) error {
+	head := Head{
+		ContentType: r.opt.MsgPackContentType,
+		Status:      status,
+		Headers:     opt.Headers,
+		Cookies:     opt.Cookies,
+	}
+
+	m := MsgPack{
+		Head: head,
+	}
+
+	return r.Render(w, m, v)
+}
+
+// CBOR marshals the given interface object and writes the CBOR response.
+func (r *Render) CBOR(w io.Writer, status int, v interface{}) error {
+	return r.cbor(w, status, v, RequestOptions{})
+}
+
+func (r *Render) cbor(w io.Writer, status int, v interface{}, opt RequestOptions) error {
+	head := Head{
+		ContentType: r.opt.CBORContentType,
+		Status:      status,
+		Headers:     opt.Headers,
+		Cookies:     opt.Cookies,
+	}
+
+	c := CBOR{
+		Head: head,
+	}
+
+	return r.Render(w, c, v)
+}
+
+// YAML marshals the given interface object and writes the YAML response.
+func (r *Render) YAML(w io.Writer, status int, v interface{}) error {
+	return r.yaml(w, status, v, RequestOptions{})
+}
+
+func (r *Render) yaml(w io.Writer, status int, v interface{}, opt RequestOptions) error {
+	head := Head{
+		ContentType: r.opt.YAMLContentType + r.compiledCharset,
+		Status:      status,
+		Headers:     opt.Headers,
+		Cookies:     opt.Cookies,
+	}
+
+	y := YAML{
+		Head:   head,
+		Indent: r.opt.IndentYAML,
+	}
+
+	return r.Render(w, y, v)
+}
+
+// withDefaultHeaders wraps w to add Options.Headers to the response.
+func (r *Render) withDefaultHeaders(w io.Writer) io.Writer {
+	hw, ok := w.(http.ResponseWriter)
+	if !ok || len(r.opt.Headers) == 0 {
+		return w
+	}
+	return &defaultHeaderWriter{ResponseWriter: hw, headers: r.opt.Headers}
+}
+
+// defaultHeaderWriter adds headers that are not set yet right before the
+// response headers are written.
+type defaultHeaderWriter struct {
+	http.ResponseWriter
+	headers     http.Header
+	wroteHeader bool
+}
+
+func (d *defaultHeaderWriter) WriteHeader(status int) {
+	if !d.wroteHeader {
+		d.wroteHeader = true
+		h := d.ResponseWriter.Header()
+		for k, v := range d.headers {
+			if _, ok := h[http.CanonicalHeaderKey(k)]; !ok {
+				h[http.CanonicalHeaderKey(k)] = append([]string(nil), v...)
+			}
+		}
+	}
+	d.ResponseWriter.WriteHeader(status)
+}
+
+func (d *defaultHeaderWriter) Write(b []byte) (int, error) {
+	if !d.wroteHeader {
+		d.WriteHeader(http.StatusOK)
+	}
+	return d.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher if the underlying ResponseWriter does.
+func (d *defaultHeaderWriter) Flush() {
+	if f, ok := d.ResponseWriter.(http.Flusher); ok {
+		if !d.wroteHeader {
+			d.WriteHeader(http.StatusOK)
+		}
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying ResponseWriter, for http.ResponseController.
+func (d *defaultHeaderWriter) Unwrap() http.ResponseWriter {
+	return d.ResponseWriter
+}
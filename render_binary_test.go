@@ -0,0 +1,121 @@
+package render
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMsgPackBasic(t *testing.T) {
+	render := New()
+
+	var err error
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err = render.MsgPack(w, 299, Greeting{"hello", "world"})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	h.ServeHTTP(res, req)
+
+	expectNil(t, err)
+	expect(t, res.Code, 299)
+	expect(t, res.Header().Get(ContentType), ContentMsgPack)
+	expect(t, res.Body.String(), "\x82\xa3one\xa5hello\xa3two\xa5world")
+}
+
+func TestMsgPackValues(t *testing.T) {
+	v := []interface{}{nil, true, false, 0, 127, 200, 70000, uint64(math.MaxUint64), -1, -32, -100, -1000, int64(math.MinInt64), 1.5, []int{}, map[string]int{}}
+
+	b, err := encodeMsgPack(v)
+
+	expectNil(t, err)
+	expect(t, string(b), "\xdc\x00\x10\xc0\xc3\xc2\x00\x7f\xcc\xc8\xce\x00\x01\x11\x70\xcf\xff\xff\xff\xff\xff\xff\xff\xff"+
+		"\xff\xe0\xd0\x9c\xd1\xfc\x18\xd3\x80\x00\x00\x00\x00\x00\x00\x00\xcb\x3f\xf8\x00\x00\x00\x00\x00\x00\x90\x80")
+}
+
+func TestMsgPackLengths(t *testing.T) {
+	b, err := encodeMsgPack(strings.Repeat("a", 40))
+	expectNil(t, err)
+	expect(t, string(b[:2]), "\xd9\x28")
+
+	b, err = encodeMsgPack(make([]int, 20))
+	expectNil(t, err)
+	expect(t, string(b[:3]), "\xdc\x00\x14")
+}
+
+func TestCBORBasic(t *testing.T) {
+	render := New()
+
+	res := httptest.NewRecorder()
+	err := render.CBOR(res, http.StatusOK, Greeting{"hello", "world"})
+
+	expectNil(t, err)
+	expect(t, res.Header().Get(ContentType), ContentCBOR)
+	expect(t, res.Body.String(), "\xa2\x63one\x65hello\x63two\x65world")
+}
+
+func TestCBORValues(t *testing.T) {
+	v := []interface{}{nil, true, false, 10, 500, -500, 1.5, strings.Repeat("a", 24), map[string]int{}}
+
+	b, err := encodeCBOR(v)
+
+	expectNil(t, err)
+	expect(t, string(b), "\x89\xf6\xf5\xf4\x0a\x19\x01\xf4\x39\x01\xf3\xfb\x3f\xf8\x00\x00\x00\x00\x00\x00\x78\x18"+strings.Repeat("a", 24)+"\xa0")
+}
+
+func TestBinaryWithError(t *testing.T) {
+	render := New()
+
+	res := httptest.NewRecorder()
+	err := render.MsgPack(res, http.StatusOK, math.NaN())
+
+	expectNotNil(t, err)
+	expect(t, res.Code, http.StatusInternalServerError)
+
+	res = httptest.NewRecorder()
+	err = render.CBOR(res, http.StatusOK, math.Inf(1))
+
+	expectNotNil(t, err)
+	expect(t, res.Code, http.StatusInternalServerError)
+}
+
+func TestNegotiateBinary(t *testing.T) {
+	render := New()
+
+	for contentType, body := range map[string]string{
+		ContentMsgPack: "\x82\xa3one\xa5hello\xa3two\xa5world",
+		ContentCBOR:    "\xa2\x63one\x65hello\x63two\x65world",
+	} {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/foo", nil)
+		req.Header.Set("Accept", contentType+", application/json;q=0.1")
+		err := render.Negotiate(res, req, http.StatusOK, Greeting{"hello", "world"})
+
+		expectNil(t, err)
+		expect(t, res.Header().Get(ContentType), contentType)
+		expect(t, res.Body.String(), body)
+	}
+}
+
+func TestBinaryCustomContentType(t *testing.T) {
+	render := New(Options{
+		MsgPackContentType: "application/x-msgpack",
+		CBORContentType:    "application/x-cbor",
+	})
+
+	res := httptest.NewRecorder()
+	err := render.MsgPack(res, http.StatusOK, "hello")
+
+	expectNil(t, err)
+	expect(t, res.Header().Get(ContentType), "application/x-msgpack")
+
+	res = httptest.NewRecorder()
+	err = render.CBOR(res, http.StatusOK, "hello")
+
+	expectNil(t, err)
+	expect(t, res.Header().Get(ContentType), "application/x-cbor")
+	expect(t, res.Body.String(), "\x65hello")
+}
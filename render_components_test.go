@@ -0,0 +1,150 @@
+package render
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+type componentPage struct {
+	Title string
+	Body  string
+}
+
+func TestHTMLComponent(t *testing.T) {
+	render := New(Options{
+		Directory: "fixtures/components",
+		Layout:    "layout",
+	})
+
+	var err error
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err = render.HTML(w, http.StatusOK, "home", componentPage{"Hello", "<world>"})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	h.ServeHTTP(res, req)
+
+	expectNil(t, err)
+	expect(t, res.Body.String(), "<main><div class=\"card\"><h2>Hello</h2><p>&lt;world&gt;</p></div>\n<span class=\"badge\">new</span>\n</main>\n")
+}
+
+func componentFS(page string) fstest.MapFS {
+	return fstest.MapFS{
+		"components/card.tmpl": {Data: []byte(`{{ props "title" "subtitle?" }}{{ slots "body" "footer?" }}<h2>{{ .title }}</h2>{{ with .subtitle }}<h3>{{ . }}</h3>{{ end }}{{ .body }}{{ with .footer }}<footer>{{ . }}</footer>{{ end }}`)},
+		"page.tmpl":            {Data: []byte(page)},
+	}
+}
+
+func TestHTMLComponentOptionalPropsAndSlots(t *testing.T) {
+	render := New(Options{
+		FS: componentFS(`{{ component "card" (dict "title" "Hi" "subtitle" "there" "body" "<b>" "footer" .) }}`),
+	})
+
+	res := httptest.NewRecorder()
+	err := render.HTML(res, http.StatusOK, "page", "bye")
+
+	expectNil(t, err)
+	expect(t, res.Body.String(), "<h2>Hi</h2><h3>there</h3>&lt;b&gt;<footer>bye</footer>")
+}
+
+func TestHTMLComponentMissingProp(t *testing.T) {
+	render := New(Options{
+		FS: componentFS(`{{ component "card" "body" "text" }}`),
+	})
+
+	res := httptest.NewRecorder()
+	err := render.HTML(res, http.StatusOK, "page", nil)
+
+	expectNotNil(t, err)
+	expect(t, res.Code, http.StatusInternalServerError)
+	expect(t, strings.Contains(err.Error(), `executing "page"`), true)
+	expect(t, strings.Contains(err.Error(), `component "card": missing prop "title"`), true)
+}
+
+func TestHTMLComponentMissingSlot(t *testing.T) {
+	render := New(Options{
+		FS: componentFS(`{{ component "card" "title" "Hi" }}`),
+	})
+
+	res := httptest.NewRecorder()
+	err := render.HTML(res, http.StatusOK, "page", nil)
+
+	expectNotNil(t, err)
+	expect(t, strings.Contains(err.Error(), `component "card": missing slot "body"`), true)
+}
+
+func TestHTMLComponentUnknownProp(t *testing.T) {
+	render := New(Options{
+		FS: componentFS(`{{ component "card" "title" "Hi" "body" "text" "colour" "red" }}`),
+	})
+
+	res := httptest.NewRecorder()
+	err := render.HTML(res, http.StatusOK, "page", nil)
+
+	expectNotNil(t, err)
+	expect(t, strings.Contains(err.Error(), `component "card": unknown prop "colour"`), true)
+}
+
+func TestHTMLComponentSlotType(t *testing.T) {
+	render := New(Options{
+		FS: componentFS(`{{ component "card" "title" "Hi" "body" 42 }}`),
+	})
+
+	res := httptest.NewRecorder()
+	err := render.HTML(res, http.StatusOK, "page", nil)
+
+	expectNotNil(t, err)
+	expect(t, strings.Contains(err.Error(), `component "card": slot "body" expects HTML, got int`), true)
+}
+
+func TestHTMLComponentNotDefined(t *testing.T) {
+	render := New(Options{
+		FS: componentFS(`{{ component "button" }}`),
+	})
+
+	res := httptest.NewRecorder()
+	err := render.HTML(res, http.StatusOK, "page", nil)
+
+	expectNotNil(t, err)
+	expect(t, strings.Contains(err.Error(), `component "button" is not defined`), true)
+}
+
+func TestStrictComponents(t *testing.T) {
+	_, err := NewWithError(Options{
+		FS: componentFS(`<h1>page</h1>
+{{ if . }}{{ component "card" (dict "body" "text") }}{{ end }}
+{{ define "footer-page" }}{{ component "button" }}{{ end }}`),
+		StrictComponents: true,
+	})
+
+	cerr, ok := err.(*CompileError)
+	expect(t, ok, true)
+	expect(t, len(cerr.Errors), 2)
+	expect(t, cerr.Errors[0].Path, "page.tmpl")
+	expect(t, cerr.Errors[0].Line, 3)
+	expect(t, cerr.Errors[0].Message, `component "button" called from "footer-page": component "button" is not defined`)
+	expect(t, cerr.Errors[1].Path, "page.tmpl")
+	expect(t, cerr.Errors[1].Line, 2)
+	expect(t, cerr.Errors[1].Message, `component "card" called from "page": missing prop "title"`)
+}
+
+func TestStrictComponentsSkipsDynamicProps(t *testing.T) {
+	_, err := NewWithError(Options{
+		FS:               componentFS(`{{ component "card" .Props }}{{ component "card" "title" "Hi" .Name "text" }}`),
+		StrictComponents: true,
+	})
+
+	expectNil(t, err)
+}
+
+func TestComponentsNotStrict(t *testing.T) {
+	_, err := NewWithError(Options{
+		FS: componentFS(`{{ component "button" }}`),
+	})
+
+	expectNil(t, err)
+}
@@ -0,0 +1,232 @@
+package render
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func compressRequest(acceptEncoding string) *http.Request {
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	return req
+}
+
+func gunzip(t *testing.T, b []byte) string {
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestCompressJSONGzip(t *testing.T) {
+	render := New(Options{
+		Compression: true,
+	})
+
+	v := map[string]string{"text": strings.Repeat("gopher ", 500)}
+	res := httptest.NewRecorder()
+	err := render.JSONRequest(res, compressRequest("gzip, deflate"), http.StatusCreated, v)
+
+	expectNil(t, err)
+	expect(t, res.Code, http.StatusCreated)
+	expect(t, res.Header().Get(ContentType), ContentJSON+"; charset=UTF-8")
+	expect(t, res.Header().Get(ContentEncoding), "gzip")
+	expect(t, res.Header().Get("Vary"), "Accept-Encoding")
+	expect(t, res.Header().Get(ContentLength), strconv.Itoa(res.Body.Len()))
+	expect(t, gunzip(t, res.Body.Bytes()), "{\"text\":\""+strings.Repeat("gopher ", 500)+"\"}")
+}
+
+func TestCompressDeflate(t *testing.T) {
+	render := New(Options{
+		Compression: true,
+	})
+
+	text := strings.Repeat("gopher ", 500)
+	res := httptest.NewRecorder()
+	err := render.TextRequest(res, compressRequest("gzip;q=0.5, deflate"), http.StatusOK, text)
+
+	expectNil(t, err)
+	expect(t, res.Header().Get(ContentEncoding), "deflate")
+
+	zr, err := zlib.NewReader(res.Body)
+	expectNil(t, err)
+	out, _ := io.ReadAll(zr)
+	expect(t, string(out), text)
+}
+
+func TestCompressBelowMinSize(t *testing.T) {
+	render := New(Options{
+		Compression: true,
+	})
+
+	res := httptest.NewRecorder()
+	err := render.TextRequest(res, compressRequest("gzip"), http.StatusOK, "Hello Text!")
+
+	expectNil(t, err)
+	expect(t, res.Header().Get(ContentEncoding), "")
+	expect(t, res.Header().Get("Vary"), "Accept-Encoding")
+	expect(t, res.Body.String(), "Hello Text!")
+}
+
+func TestCompressMinSize(t *testing.T) {
+	render := New(Options{
+		Directory:          "fixtures/basic",
+		Compression:        true,
+		CompressionMinSize: 10,
+	})
+
+	res := httptest.NewRecorder()
+	err := render.HTMLRequest(res, compressRequest("*"), http.StatusOK, "hello", "gophers")
+
+	expectNil(t, err)
+	expect(t, res.Header().Get(ContentEncoding), "gzip")
+	expect(t, gunzip(t, res.Body.Bytes()), "<h1>Hello gophers</h1>\n")
+}
+
+func TestCompressNotAccepted(t *testing.T) {
+	render := New(Options{
+		Compression: true,
+	})
+
+	text := strings.Repeat("gopher ", 500)
+	for _, acceptEncoding := range []string{"", "br", "gzip;q=0, deflate;q=0", "*;q=0"} {
+		res := httptest.NewRecorder()
+		err := render.TextRequest(res, compressRequest(acceptEncoding), http.StatusOK, text)
+
+		expectNil(t, err)
+		expect(t, res.Header().Get(ContentEncoding), "")
+		expect(t, res.Header().Get("Vary"), "Accept-Encoding")
+		expect(t, res.Body.String(), text)
+	}
+}
+
+func TestCompressContentTypeNotAllowed(t *testing.T) {
+	render := New(Options{
+		Compression: true,
+	})
+
+	data := bytes.Repeat([]byte("gopher "), 500)
+	res := httptest.NewRecorder()
+	err := render.DataRequest(res, compressRequest("gzip"), http.StatusOK, data)
+
+	expectNil(t, err)
+	expect(t, res.Header().Get(ContentEncoding), "")
+	expect(t, res.Body.String(), string(data))
+}
+
+func TestCompressData(t *testing.T) {
+	render := New(Options{
+		Compression:      true,
+		CompressionTypes: []string{"application/octet-stream"},
+	})
+
+	data := bytes.Repeat([]byte("gopher "), 500)
+	res := httptest.NewRecorder()
+	err := render.DataRequest(res, compressRequest("gzip"), http.StatusOK, data)
+
+	expectNil(t, err)
+	expect(t, res.Header().Get(ContentEncoding), "gzip")
+	expect(t, res.Header().Get(ContentLength), "")
+	expect(t, gunzip(t, res.Body.Bytes()), string(data))
+}
+
+func TestCompressStreamingJSON(t *testing.T) {
+	render := New(Options{
+		Compression:   true,
+		StreamingJSON: true,
+	})
+
+	v := map[string]string{"text": strings.Repeat("gopher ", 500)}
+	res := httptest.NewRecorder()
+	err := render.JSONRequest(res, compressRequest("gzip"), http.StatusOK, v)
+
+	expectNil(t, err)
+	expect(t, res.Header().Get(ContentEncoding), "gzip")
+	expect(t, res.Header().Get(ContentLength), "")
+	expect(t, gunzip(t, res.Body.Bytes()), "{\"text\":\""+strings.Repeat("gopher ", 500)+"\"}\n")
+}
+
+func TestCompressStreamingHTML(t *testing.T) {
+	render := New(Options{
+		Directory:     "fixtures/basic",
+		Compression:   true,
+		StreamingHTML: true,
+	})
+
+	res := httptest.NewRecorder()
+	err := render.HTMLRequest(res, compressRequest("gzip"), http.StatusOK, "hello", "gophers", HTMLOptions{
+		Layout: "layout",
+	})
+
+	expectNil(t, err)
+	expect(t, res.Flushed, true)
+	expect(t, res.Header().Get(ContentEncoding), "gzip")
+
+	direct := httptest.NewRecorder()
+	render.HTML(direct, http.StatusOK, "hello", "gophers", HTMLOptions{
+		Layout: "layout",
+	})
+	expect(t, gunzip(t, res.Body.Bytes()), direct.Body.String())
+}
+
+func TestCompressDisabled(t *testing.T) {
+	render := New()
+
+	text := strings.Repeat("gopher ", 500)
+	res := httptest.NewRecorder()
+	err := render.TextRequest(res, compressRequest("gzip"), http.StatusOK, text)
+
+	expectNil(t, err)
+	expect(t, res.Header().Get(ContentEncoding), "")
+	expect(t, res.Header().Get("Vary"), "")
+	expect(t, res.Body.String(), text)
+}
+
+func TestCompressError(t *testing.T) {
+	render := New(Options{
+		Compression: true,
+	})
+
+	res := httptest.NewRecorder()
+	err := render.JSONRequest(res, compressRequest("gzip"), http.StatusOK, math.NaN())
+
+	expectNotNil(t, err)
+	expect(t, res.Code, http.StatusInternalServerError)
+	expect(t, res.Header().Get(ContentEncoding), "")
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := map[string]string{
+		"":                      "",
+		"gzip":                  "gzip",
+		"deflate, gzip":         "gzip",
+		"deflate":               "deflate",
+		"gzip;q=0.2, deflate":   "deflate",
+		"GZIP":                  "gzip",
+		"*":                     "gzip",
+		"*, gzip;q=0":           "deflate",
+		"br, identity":          "",
+		"gzip;q=0, deflate;q=0": "",
+	}
+
+	for acceptEncoding, expected := range tests {
+		if encoding := negotiateEncoding(acceptEncoding); encoding != expected {
+			t.Errorf("%q: expected %q, got %q", acceptEncoding, expected, encoding)
+		}
+	}
+}
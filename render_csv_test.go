@@ -0,0 +1,227 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type csvAudit struct {
+	Created time.Time `csv:"created"`
+}
+
+type csvRow struct {
+	ID     int     `json:"id"`
+	Name   string  `csv:"name" json:"full_name"`
+	Score  float64 `json:"score,omitempty"`
+	Active bool
+	Note   *string `json:"note"`
+	Secret string  `json:"-"`
+	hidden string
+	csvAudit
+}
+
+func TestCSVStructs(t *testing.T) {
+	render := New()
+	note := "has, comma"
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	rows := []csvRow{
+		{ID: 1, Name: "Ann", Score: 9.5, Active: true, Note: &note, Secret: "x", hidden: "y", csvAudit: csvAudit{created}},
+		{ID: 2, Name: "Bob \"B\""},
+	}
+
+	var err error
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err = render.CSV(w, http.StatusOK, rows)
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	h.ServeHTTP(res, req)
+
+	expectNil(t, err)
+	expect(t, res.Code, http.StatusOK)
+	expect(t, res.Header().Get(ContentType), ContentCSV+"; charset=UTF-8")
+	expect(t, res.Header().Get("Content-Disposition"), "")
+	expect(t, res.Body.String(), "id,name,score,Active,note,created\n"+
+		"1,Ann,9.5,true,\"has, comma\",2020-01-02T03:04:05Z\n"+
+		"2,\"Bob \"\"B\"\"\",0,false,,0001-01-01T00:00:00Z\n")
+}
+
+func TestCSVColumns(t *testing.T) {
+	render := New()
+
+	rows := []*csvRow{{ID: 1, Name: "Ann"}, nil}
+
+	res := httptest.NewRecorder()
+	err := render.CSV(res, http.StatusOK, rows, CSVOptions{
+		Columns:  []string{"name", "id"},
+		Filename: "report.csv",
+		Headers:  http.Header{"Cache-Control": {"no-store"}},
+		Cookies:  []*http.Cookie{{Name: "export", Value: "done"}},
+	})
+
+	expectNil(t, err)
+	expect(t, res.Header().Get("Content-Disposition"), `attachment; filename="report.csv"`)
+	expect(t, res.Header().Get("Cache-Control"), "no-store")
+	expect(t, res.Header().Get("Set-Cookie"), "export=done")
+	expect(t, res.Body.String(), "name,id\nAnn,1\n,\n")
+}
+
+func TestCSVUnknownColumn(t *testing.T) {
+	render := New()
+
+	res := httptest.NewRecorder()
+	err := render.CSV(res, http.StatusOK, []csvRow{}, CSVOptions{
+		Columns: []string{"missing"},
+	})
+
+	expectNotNil(t, err)
+	expect(t, res.Code, http.StatusInternalServerError)
+}
+
+func TestCSVMaps(t *testing.T) {
+	render := New()
+
+	rows := []map[string]interface{}{
+		{"b": 2, "a": "one"},
+		{"c": true},
+	}
+
+	res := httptest.NewRecorder()
+	err := render.CSV(res, http.StatusOK, rows)
+
+	expectNil(t, err)
+	expect(t, res.Body.String(), "a,b,c\none,2,\n,,true\n")
+}
+
+func TestCSVNoColumns(t *testing.T) {
+	render := New()
+
+	for _, rows := range []interface{}{[]map[string]string{}, []map[string]string{{}, {}}, []interface{}{}} {
+		res := httptest.NewRecorder()
+		err := render.CSV(res, http.StatusOK, rows)
+
+		expectNil(t, err)
+		expect(t, res.Code, http.StatusOK)
+		expect(t, res.Header().Get(ContentType), ContentCSV+"; charset=UTF-8")
+		expect(t, res.Body.String(), "")
+	}
+}
+
+func TestCSVNotASlice(t *testing.T) {
+	render := New()
+
+	res := httptest.NewRecorder()
+	err := render.CSV(res, http.StatusOK, csvRow{})
+
+	expectNotNil(t, err)
+	expect(t, res.Code, http.StatusInternalServerError)
+
+	res = httptest.NewRecorder()
+	err = render.CSV(res, http.StatusOK, []int{1, 2})
+
+	expectNotNil(t, err)
+}
+
+func TestCSVChannel(t *testing.T) {
+	render := New()
+
+	rows := make(chan Greeting, 2)
+	rows <- Greeting{"hello", "world"}
+	rows <- Greeting{"a", "b"}
+	close(rows)
+
+	res := httptest.NewRecorder()
+	err := render.CSV(res, http.StatusOK, rows)
+
+	expectNil(t, err)
+	expect(t, res.Body.String(), "one,two\nhello,world\na,b\n")
+}
+
+func TestCSVIterator(t *testing.T) {
+	render := New()
+
+	rows := Iterator(func(yield func(v interface{}) error) error {
+		for i := 1; i <= 3; i++ {
+			if err := yield(map[string]int{"n": i, "square": i * i}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	res := httptest.NewRecorder()
+	err := render.CSV(res, http.StatusOK, rows)
+
+	expectNil(t, err)
+	expect(t, res.Code, http.StatusOK)
+	expect(t, res.Header().Get(ContentType), ContentCSV+"; charset=UTF-8")
+	expect(t, res.Body.String(), "n,square\n1,1\n2,4\n3,9\n")
+}
+
+func TestCSVFlushEvery(t *testing.T) {
+	res := httptest.NewRecorder()
+	var flushed []string
+	rows := Iterator(func(yield func(v interface{}) error) error {
+		for i := 0; i < 5; i++ {
+			flushed = append(flushed, fmt.Sprint(res.Flushed, strings.Count(res.Body.String(), "\n")))
+			if err := yield(Greeting{"x", "y"}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	c := CSV{Head: Head{ContentType: ContentCSV, Status: http.StatusOK}, FlushEvery: 2}
+	err := c.Render(res, rows)
+
+	expectNil(t, err)
+	expect(t, strings.Join(flushed, " "), "false 0 false 0 true 3 true 3 true 5")
+	expect(t, strings.Count(res.Body.String(), "\n"), 6)
+}
+
+func TestCSVStreamError(t *testing.T) {
+	render := New()
+
+	rows := Iterator(func(yield func(v interface{}) error) error {
+		if err := yield(Greeting{"hello", "world"}); err != nil {
+			return err
+		}
+		return errFail
+	})
+
+	res := httptest.NewRecorder()
+	err := render.CSV(res, http.StatusOK, rows)
+
+	var streamErr *StreamError
+	expect(t, errors.As(err, &streamErr), true)
+	expect(t, streamErr.Written, 1)
+	expect(t, errors.Is(err, errFail), true)
+	expect(t, res.Code, http.StatusOK)
+	expect(t, res.Body.String(), "one,two\nhello,world\n")
+}
+
+func TestTSV(t *testing.T) {
+	render := New()
+
+	rows := []Greeting{{"hello", "world"}, {"tab\there", "x"}}
+
+	res := httptest.NewRecorder()
+	err := render.TSV(res, http.StatusOK, rows)
+
+	expectNil(t, err)
+	expect(t, res.Header().Get(ContentType), ContentTSV+"; charset=UTF-8")
+	expect(t, res.Body.String(), "one\ttwo\nhello\tworld\n\"tab\there\"\tx\n")
+}
+
+func TestContentDisposition(t *testing.T) {
+	expect(t, contentDisposition("attachment", ""), "attachment")
+	expect(t, contentDisposition("attachment", "a \"b\".csv"), `attachment; filename="a \"b\".csv"`)
+	expect(t, contentDisposition("inline", "résumé 1.pdf"), `inline; filename="r_sum_ 1.pdf"; filename*=UTF-8''r%C3%A9sum%C3%A9%201.pdf`)
+}
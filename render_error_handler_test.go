@@ -0,0 +1,138 @@
+package render
+
+import (
+	"errors"
+	"html/template"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var errFail = errors.New("fail")
+
+var failFuncs = []template.FuncMap{
+	{
+		"fail": func() (string, error) {
+			return "", errFail
+		},
+	},
+}
+
+func TestErrorHandlerHTMLPage(t *testing.T) {
+	var render *Render
+	var handled *RenderError
+	render = New(Options{
+		Directory: "fixtures/errors",
+		Funcs:     failFuncs,
+		ErrorHandler: func(w io.Writer, e *RenderError) error {
+			handled = e
+			return render.HTML(w, http.StatusInternalServerError, "error", map[string]int{"Status": 500})
+		},
+	})
+
+	res := httptest.NewRecorder()
+	err := render.HTML(res, http.StatusOK, "page", nil)
+
+	expectNil(t, err)
+	expectNotNil(t, handled)
+	expect(t, errors.Is(handled, errFail), true)
+	expect(t, handled.HeadersWritten, false)
+	_, ok := handled.Engine.(HTML)
+	expect(t, ok, true)
+	expect(t, res.Code, http.StatusInternalServerError)
+	expect(t, res.Body.String(), "<h1>Oops: 500</h1>\n")
+}
+
+func TestErrorHandlerJSONEnvelope(t *testing.T) {
+	var render *Render
+	render = New(Options{
+		ErrorHandler: func(w io.Writer, e *RenderError) error {
+			return render.JSON(w, http.StatusInternalServerError, map[string]interface{}{
+				"error": map[string]string{"code": "render_failed"},
+			})
+		},
+	})
+
+	res := httptest.NewRecorder()
+	err := render.JSON(res, http.StatusOK, errorMarshaler{})
+
+	expectNil(t, err)
+	expect(t, res.Code, http.StatusInternalServerError)
+	expect(t, res.Header().Get(ContentType), ContentJSON+"; charset=UTF-8")
+	expect(t, res.Body.String(), `{"error":{"code":"render_failed"}}`)
+}
+
+func TestErrorHandlerReRaise(t *testing.T) {
+	var logged []error
+	render := New(Options{
+		Directory: "fixtures/errors",
+		Funcs:     failFuncs,
+		ErrorHandler: func(w io.Writer, e *RenderError) error {
+			logged = append(logged, e.Err)
+			return e.Err
+		},
+	})
+
+	res := httptest.NewRecorder()
+	err := render.HTML(res, http.StatusOK, "page", nil)
+
+	expect(t, errors.Is(err, errFail), true)
+	expect(t, len(logged), 1)
+	expect(t, res.Code, http.StatusOK)
+	expect(t, res.Body.String(), "")
+}
+
+func TestErrorHandlerHeadersWritten(t *testing.T) {
+	var handled *RenderError
+	render := New(Options{
+		StreamingJSON: true,
+		ErrorHandler: func(w io.Writer, e *RenderError) error {
+			handled = e
+			return e.Err
+		},
+	})
+
+	res := httptest.NewRecorder()
+	err := render.JSON(res, http.StatusCreated, errorMarshaler{})
+
+	expectNotNil(t, err)
+	expect(t, handled.HeadersWritten, true)
+	expect(t, res.Code, http.StatusCreated)
+}
+
+func TestErrorHandlerWithoutResponseWriter(t *testing.T) {
+	var handled *RenderError
+	render := New(Options{
+		StreamingJSON: true,
+		ErrorHandler: func(w io.Writer, e *RenderError) error {
+			handled = e
+			return nil
+		},
+	})
+
+	err := render.JSON(io.Discard, http.StatusOK, errorMarshaler{})
+
+	expectNil(t, err)
+	expect(t, handled.HeadersWritten, false)
+}
+
+func TestErrorHandlerFailingErrorPage(t *testing.T) {
+	var render *Render
+	calls := 0
+	render = New(Options{
+		Directory: "fixtures/errors",
+		Funcs:     failFuncs,
+		ErrorHandler: func(w io.Writer, e *RenderError) error {
+			calls++
+			return render.HTML(w, http.StatusInternalServerError, "page", nil)
+		},
+	})
+
+	res := httptest.NewRecorder()
+	err := render.HTML(res, http.StatusOK, "page", nil)
+
+	expect(t, errors.Is(err, errFail), true)
+	expect(t, calls, 1)
+	expect(t, res.Code, http.StatusInternalServerError)
+}
@@ -0,0 +1,103 @@
+package render
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewWithErrorCompileError(t *testing.T) {
+	r, err := NewWithError(Options{
+		Directory: "fixtures/broken",
+	})
+
+	expect(t, r == nil, true)
+	cerr, ok := err.(*CompileError)
+	expect(t, ok, true)
+	expect(t, len(cerr.Errors), 2)
+
+	expect(t, cerr.Errors[0].Path, filepath.Join("fixtures", "broken", "unclosed.tmpl"))
+	expect(t, cerr.Errors[0].Line, 4)
+	expect(t, cerr.Errors[0].Message, "unexpected EOF")
+
+	expect(t, cerr.Errors[1].Path, filepath.Join("fixtures", "broken", "undefined.tmpl"))
+	expect(t, cerr.Errors[1].Line, 1)
+	expect(t, cerr.Errors[1].Message, `function "missingFunc" not defined`)
+}
+
+func TestNewWithErrorValid(t *testing.T) {
+	r, err := NewWithError(Options{
+		Directory: "fixtures/basic",
+	})
+
+	expectNil(t, err)
+	expect(t, r.TemplateLookup("hello") != nil, true)
+}
+
+func TestNewPanicsOnCompileError(t *testing.T) {
+	defer func() {
+		_, ok := recover().(*CompileError)
+		expect(t, ok, true)
+	}()
+
+	New(Options{
+		Directory: "fixtures/broken",
+	})
+}
+
+func TestNewWithErrorAssetError(t *testing.T) {
+	_, err := NewWithError(Options{
+		Asset: func(file string) ([]byte, error) {
+			return nil, os.ErrNotExist
+		},
+		AssetNames: func() []string {
+			return []string{"templates/test.tmpl"}
+		},
+	})
+
+	cerr, ok := err.(*CompileError)
+	expect(t, ok, true)
+	expect(t, len(cerr.Errors), 1)
+	expect(t, cerr.Errors[0].Path, "templates/test.tmpl")
+	expect(t, cerr.Errors[0].Line, 0)
+	expect(t, cerr.Errors[0].Err, os.ErrNotExist)
+}
+
+func TestRecompileKeepsPreviousTemplates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "render")
+	expectNil(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "hello.tmpl")
+	expectNil(t, ioutil.WriteFile(file, []byte("<h1>Hello {{ . }}</h1>"), 0644))
+
+	render := New(Options{
+		Directory:     dir,
+		IsDevelopment: true,
+	})
+
+	expectNil(t, ioutil.WriteFile(file, []byte("<h1>Hello {{ . </h1>"), 0644))
+
+	cerr, ok := render.Recompile().(*CompileError)
+	expect(t, ok, true)
+	expect(t, len(cerr.Errors), 1)
+	expect(t, cerr.Errors[0].Path, file)
+
+	res := httptest.NewRecorder()
+	err = render.HTML(res, http.StatusOK, "hello", "gophers")
+
+	expectNil(t, err)
+	expect(t, res.Code, http.StatusOK)
+	expect(t, res.Body.String(), "<h1>Hello gophers</h1>")
+
+	expectNil(t, ioutil.WriteFile(file, []byte("<h2>Hello {{ . }}</h2>"), 0644))
+
+	res = httptest.NewRecorder()
+	err = render.HTML(res, http.StatusOK, "hello", "gophers")
+
+	expectNil(t, err)
+	expect(t, res.Body.String(), "<h2>Hello gophers</h2>")
+}
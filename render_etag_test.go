@@ -0,0 +1,224 @@
+package render
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestETagStrong(t *testing.T) {
+	render := New(Options{
+		ETag: ETagStrong,
+	})
+
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	res := httptest.NewRecorder()
+	err := render.JSONRequest(res, req, http.StatusOK, Greeting{"hello", "world"})
+
+	expectNil(t, err)
+	expect(t, res.Code, http.StatusOK)
+	expect(t, res.Header().Get("ETag"), computeETag([]byte("{\"one\":\"hello\",\"two\":\"world\"}"), false))
+	expect(t, res.Body.String(), "{\"one\":\"hello\",\"two\":\"world\"}")
+}
+
+func TestETagNotModified(t *testing.T) {
+	render := New(Options{
+		ETag: ETagStrong,
+	})
+
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	res := httptest.NewRecorder()
+	render.TextRequest(res, req, http.StatusOK, "Hello Text!")
+	etag := res.Header().Get("ETag")
+
+	req.Header.Set("If-None-Match", `"other", W/`+etag)
+	res = httptest.NewRecorder()
+	err := render.TextRequest(res, req, http.StatusOK, "Hello Text!")
+
+	expectNil(t, err)
+	expect(t, res.Code, http.StatusNotModified)
+	expect(t, res.Header().Get("ETag"), etag)
+	expect(t, res.Header().Get(ContentType), "")
+	expect(t, res.Body.String(), "")
+}
+
+func TestETagModified(t *testing.T) {
+	render := New(Options{
+		ETag: ETagWeak,
+	})
+
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	req.Header.Set("If-None-Match", `W/"stale"`)
+	res := httptest.NewRecorder()
+	err := render.XMLRequest(res, req, http.StatusOK, GreetingXML{One: "hello", Two: "world"})
+
+	expectNil(t, err)
+	expect(t, res.Code, http.StatusOK)
+	expect(t, strings.HasPrefix(res.Header().Get("ETag"), `W/"`), true)
+	expect(t, res.Body.String(), "<greeting one=\"hello\" two=\"world\"></greeting>")
+}
+
+func TestETagPerCall(t *testing.T) {
+	render := New(Options{
+		ETag: ETagStrong,
+	})
+
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	res := httptest.NewRecorder()
+	err := render.DataRequest(res, req, http.StatusOK, []byte("hello"), RequestOptions{
+		ETag: ETagDisabled,
+	})
+
+	expectNil(t, err)
+	expect(t, res.Header().Get("ETag"), "")
+
+	render = New()
+	res = httptest.NewRecorder()
+	err = render.DataRequest(res, req, http.StatusOK, []byte("hello"), RequestOptions{
+		ETag: ETagWeak,
+	})
+
+	expectNil(t, err)
+	expect(t, res.Header().Get("ETag"), computeETag([]byte("hello"), true))
+}
+
+func TestETagHTML(t *testing.T) {
+	render := New(Options{
+		Directory: "fixtures/basic",
+	})
+
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	req.Header.Set("If-None-Match", computeETag([]byte("<h1>Hello gophers</h1>\n"), false))
+	res := httptest.NewRecorder()
+	err := render.HTMLRequest(res, req, http.StatusOK, "hello", "gophers", HTMLOptions{
+		ETag: ETagStrong,
+	})
+
+	expectNil(t, err)
+	expect(t, res.Code, http.StatusNotModified)
+	expect(t, res.Body.String(), "")
+}
+
+func TestETagOnlyForOK(t *testing.T) {
+	render := New(Options{
+		ETag: ETagStrong,
+	})
+
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	req.Header.Set("If-None-Match", "*")
+	res := httptest.NewRecorder()
+	err := render.TextRequest(res, req, http.StatusNotFound, "Not Found")
+
+	expectNil(t, err)
+	expect(t, res.Code, http.StatusNotFound)
+	expect(t, res.Header().Get("ETag"), "")
+	expect(t, res.Body.String(), "Not Found")
+}
+
+func TestETagIgnoredForPost(t *testing.T) {
+	render := New(Options{
+		ETag: ETagStrong,
+	})
+
+	req, _ := http.NewRequest("POST", "/foo", nil)
+	req.Header.Set("If-None-Match", "*")
+	res := httptest.NewRecorder()
+	err := render.TextRequest(res, req, http.StatusOK, "Hello Text!")
+
+	expectNil(t, err)
+	expect(t, res.Code, http.StatusOK)
+	expect(t, res.Body.String(), "Hello Text!")
+}
+
+func TestETagStreamingJSON(t *testing.T) {
+	render := New(Options{
+		ETag:          ETagStrong,
+		StreamingJSON: true,
+	})
+
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	req.Header.Set("If-None-Match", "*")
+	res := httptest.NewRecorder()
+	err := render.JSONRequest(res, req, http.StatusOK, Greeting{"hello", "world"})
+
+	expectNil(t, err)
+	expect(t, res.Code, http.StatusOK)
+	expect(t, res.Header().Get("ETag"), "")
+}
+
+func TestIfModifiedSince(t *testing.T) {
+	render := New()
+	modified := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	req.Header.Set("If-Modified-Since", modified.Format(http.TimeFormat))
+	res := httptest.NewRecorder()
+	err := render.TextRequest(res, req, http.StatusOK, "Hello Text!", RequestOptions{
+		LastModified: modified,
+	})
+
+	expectNil(t, err)
+	expect(t, res.Code, http.StatusNotModified)
+	expect(t, res.Header().Get("Last-Modified"), modified.Format(http.TimeFormat))
+
+	res = httptest.NewRecorder()
+	err = render.TextRequest(res, req, http.StatusOK, "Hello Text!", RequestOptions{
+		LastModified: modified.Add(time.Second),
+	})
+
+	expectNil(t, err)
+	expect(t, res.Code, http.StatusOK)
+	expect(t, res.Body.String(), "Hello Text!")
+}
+
+func TestIfModifiedSinceFromHeader(t *testing.T) {
+	render := New()
+	modified := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	req.Header.Set("If-Modified-Since", modified.Add(time.Hour).Format(http.TimeFormat))
+	res := httptest.NewRecorder()
+	res.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+	err := render.TextRequest(res, req, http.StatusOK, "Hello Text!")
+
+	expectNil(t, err)
+	expect(t, res.Code, http.StatusNotModified)
+}
+
+func TestETagWithCompression(t *testing.T) {
+	render := New(Options{
+		ETag:        ETagStrong,
+		Compression: true,
+	})
+
+	text := strings.Repeat("gopher ", 500)
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	res := httptest.NewRecorder()
+	err := render.TextRequest(res, req, http.StatusOK, text)
+
+	expectNil(t, err)
+	expect(t, res.Header().Get(ContentEncoding), "gzip")
+	etag := res.Header().Get("ETag")
+	expect(t, etag, "W/"+computeETag([]byte(text), false))
+
+	req.Header.Set("If-None-Match", etag)
+	res = httptest.NewRecorder()
+	err = render.TextRequest(res, req, http.StatusOK, text)
+
+	expectNil(t, err)
+	expect(t, res.Code, http.StatusNotModified)
+	expect(t, res.Header().Get("ETag"), etag)
+	expect(t, res.Header().Get(ContentEncoding), "")
+	expect(t, res.Body.Len(), 0)
+
+	req.Header.Del("Accept-Encoding")
+	res = httptest.NewRecorder()
+	err = render.TextRequest(res, req, http.StatusOK, text)
+
+	expectNil(t, err)
+	expect(t, res.Code, http.StatusNotModified)
+	expect(t, res.Header().Get("ETag"), computeETag([]byte(text), false))
+}
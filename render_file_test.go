@@ -0,0 +1,277 @@
+package render
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestDownloadBasic(t *testing.T) {
+	render := New()
+
+	var err error
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err = render.Download(w, r, strings.NewReader("hello world"), FileOptions{Filename: "hello.txt"})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	h.ServeHTTP(res, req)
+
+	expectNil(t, err)
+	expect(t, res.Code, http.StatusOK)
+	expect(t, res.Header().Get(ContentType), "text/plain; charset=utf-8")
+	expect(t, res.Header().Get("Content-Disposition"), `attachment; filename="hello.txt"`)
+	expect(t, res.Header().Get(ContentLength), "11")
+	expect(t, res.Header().Get("Accept-Ranges"), "bytes")
+	expect(t, res.Body.String(), "hello world")
+}
+
+func TestDownloadSniffsContentType(t *testing.T) {
+	render := New()
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	err := render.Download(res, req, strings.NewReader("<html><body>hi</body></html>"))
+
+	expectNil(t, err)
+	expect(t, res.Header().Get(ContentType), "text/html; charset=utf-8")
+	expect(t, res.Header().Get("Content-Disposition"), "attachment")
+}
+
+func TestDownloadOptions(t *testing.T) {
+	render := New()
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	err := render.Download(res, req, strings.NewReader("%PDF-1.4"), FileOptions{
+		Filename:    "Überblick 2024.pdf",
+		Inline:      true,
+		ContentType: "application/pdf",
+		Headers:     http.Header{"Cache-Control": {"private"}},
+		Cookies:     []*http.Cookie{{Name: "downloaded", Value: "1"}},
+	})
+
+	expectNil(t, err)
+	expect(t, res.Header().Get(ContentType), "application/pdf")
+	expect(t, res.Header().Get("Content-Disposition"), `inline; filename="_berblick 2024.pdf"; filename*=UTF-8''%C3%9Cberblick%202024.pdf`)
+	expect(t, res.Header().Get("Cache-Control"), "private")
+	expect(t, res.Header().Get("Set-Cookie"), "downloaded=1")
+}
+
+func TestDownloadRange(t *testing.T) {
+	render := New()
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	req.Header.Set("Range", "bytes=6-")
+	err := render.Download(res, req, strings.NewReader("hello world"), FileOptions{Filename: "hello.txt"})
+
+	expectNil(t, err)
+	expect(t, res.Code, http.StatusPartialContent)
+	expect(t, res.Header().Get("Content-Range"), "bytes 6-10/11")
+	expect(t, res.Header().Get("Content-Disposition"), `attachment; filename="hello.txt"`)
+	expect(t, res.Body.String(), "world")
+}
+
+func TestDownloadRangeNotSatisfiable(t *testing.T) {
+	render := New()
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	req.Header.Set("Range", "bytes=20-")
+	err := render.Download(res, req, strings.NewReader("hello world"))
+
+	expectNil(t, err)
+	expect(t, res.Code, http.StatusRequestedRangeNotSatisfiable)
+	expect(t, res.Header().Get("Content-Range"), "bytes */11")
+}
+
+func TestDownloadIfRange(t *testing.T) {
+	render := New()
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	req.Header.Set("Range", "bytes=6-")
+	req.Header.Set("If-Range", modTime.Format(http.TimeFormat))
+	err := render.Download(res, req, strings.NewReader("hello world"), FileOptions{LastModified: modTime})
+
+	expectNil(t, err)
+	expect(t, res.Code, http.StatusPartialContent)
+	expect(t, res.Body.String(), "world")
+
+	res = httptest.NewRecorder()
+	req.Header.Set("If-Range", `"v1"`)
+	err = render.Download(res, req, strings.NewReader("hello world"), FileOptions{
+		Headers: http.Header{"Etag": {`"v2"`}},
+	})
+
+	expectNil(t, err)
+	expect(t, res.Code, http.StatusOK)
+	expect(t, res.Body.String(), "hello world")
+}
+
+func TestDownloadNotModified(t *testing.T) {
+	render := New()
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	req.Header.Set("If-Modified-Since", modTime.Format(http.TimeFormat))
+	err := render.Download(res, req, strings.NewReader("hello world"), FileOptions{LastModified: modTime})
+
+	expectNil(t, err)
+	expect(t, res.Code, http.StatusNotModified)
+	expect(t, res.Body.Len(), 0)
+}
+
+type failingReader struct {
+	io.ReadSeeker
+}
+
+func (failingReader) Read(p []byte) (int, error) {
+	return 0, errors.New("disk failed")
+}
+
+func TestDownloadReadError(t *testing.T) {
+	render := New()
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	err := render.Download(res, req, failingReader{strings.NewReader("hello world")}, FileOptions{ContentType: ContentText})
+
+	var streamErr *StreamError
+	expect(t, errors.As(err, &streamErr), true)
+	expect(t, res.Code, http.StatusOK)
+	expect(t, res.Body.Len(), 0)
+}
+
+func TestFileFromFileSystem(t *testing.T) {
+	render := New(Options{
+		FileDirectory: "fixtures/file",
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	req.Header.Set("Range", "bytes=0-7")
+	err := render.File(res, req, "report.csv")
+
+	expectNil(t, err)
+	expect(t, res.Code, http.StatusPartialContent)
+	expect(t, res.Header().Get("Content-Disposition"), `attachment; filename="report.csv"`)
+	expectNotNil(t, res.Header().Get("Last-Modified"))
+	expect(t, res.Body.String(), "id,name\n")
+}
+
+func TestFileFromFS(t *testing.T) {
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	render := New(Options{
+		FS: fstest.MapFS{
+			"exports/data.json": {Data: []byte(`{"hello":"world"}`), ModTime: modTime},
+		},
+		FileDirectory: ".",
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	err := render.File(res, req, "exports/data.json", FileOptions{Filename: "export.json", Inline: true})
+
+	expectNil(t, err)
+	expect(t, res.Header().Get(ContentType), "application/json")
+	expect(t, res.Header().Get("Content-Disposition"), `inline; filename="export.json"`)
+	expect(t, res.Header().Get("Last-Modified"), modTime.Format(http.TimeFormat))
+	expect(t, res.Body.String(), `{"hello":"world"}`)
+}
+
+// readOnlyFileSystem is a FileSystem that is not an http.FileSystem.
+type readOnlyFileSystem struct{}
+
+func (readOnlyFileSystem) Walk(root string, walkFn filepath.WalkFunc) error {
+	return LocalFileSystem{}.Walk(root, walkFn)
+}
+
+func (readOnlyFileSystem) ReadFile(filename string) ([]byte, error) {
+	return LocalFileSystem{}.ReadFile(filename)
+}
+
+func TestFileReadFromFileSystem(t *testing.T) {
+	render := New(Options{
+		FileSystem:    readOnlyFileSystem{},
+		FileDirectory: "fixtures/file",
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	err := render.File(res, req, "report.csv")
+
+	expectNil(t, err)
+	expect(t, res.Header().Get("Last-Modified"), "")
+	expect(t, res.Body.String(), "id,name\n1,alpha\n2,beta\n")
+}
+
+func TestFileNotFound(t *testing.T) {
+	render := New(Options{
+		FileDirectory: "fixtures/file",
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	err := render.File(res, req, "missing.csv")
+
+	expectNotNil(t, err)
+	expect(t, res.Code, http.StatusNotFound)
+	expect(t, res.Header().Get("Content-Disposition"), "")
+}
+
+func TestFileRootedAtFileDirectory(t *testing.T) {
+	render := New(Options{
+		FileDirectory: "fixtures/file",
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	err := render.File(res, req, "/report.csv")
+
+	expectNil(t, err)
+	expect(t, res.Body.String(), "id,name\n1,alpha\n2,beta\n")
+
+	for _, name := range []string{"../basic/hello.tmpl", "../../render.go", "/../render.go", "sub/../../basic/hello.tmpl"} {
+		res := httptest.NewRecorder()
+		err := render.File(res, req, name)
+
+		expect(t, errors.Is(err, fs.ErrNotExist), true)
+		expect(t, res.Code, http.StatusNotFound)
+	}
+}
+
+func TestFileDirectory(t *testing.T) {
+	render := New(Options{
+		FileDirectory: "fixtures",
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	err := render.File(res, req, "file")
+
+	expect(t, errors.Is(err, fs.ErrNotExist), true)
+	expect(t, res.Code, http.StatusNotFound)
+}
+
+func TestFileWithoutFileDirectory(t *testing.T) {
+	render := New()
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	err := render.File(res, req, "go.mod")
+
+	expect(t, err, ErrNoFileDirectory)
+	expect(t, res.Code, http.StatusNotFound)
+}
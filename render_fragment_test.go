@@ -0,0 +1,167 @@
+package render
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFragment(t *testing.T) {
+	render := New(Options{
+		Directory: "fixtures/fragments",
+		Layout:    "layout",
+	})
+
+	var err error
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err = render.Fragment(w, http.StatusOK, "users", "list", []string{"alice", "bob"})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	h.ServeHTTP(res, req)
+
+	expectNil(t, err)
+	expect(t, res.Code, http.StatusOK)
+	expect(t, res.Header().Get(ContentType), ContentHTML+"; charset=UTF-8")
+	expect(t, res.Body.String(), "<ul><li>alice</li><li>bob</li></ul>")
+}
+
+func TestFragmentWithPageSuffix(t *testing.T) {
+	render := New(Options{
+		Directory: "fixtures/fragments",
+		Layout:    "layout",
+	})
+
+	res := httptest.NewRecorder()
+	err := render.Fragment(res, http.StatusOK, "users", "count", []string{"alice", "bob"})
+
+	expectNil(t, err)
+	expect(t, res.Body.String(), "2 users")
+}
+
+func TestFragmentWithPartialAndSection(t *testing.T) {
+	render := New(Options{
+		Directory: "fixtures/fragments",
+		Layout:    "layout",
+	})
+
+	res := httptest.NewRecorder()
+	err := render.Fragment(res, http.StatusOK, "posts", "feed", "hello")
+
+	expectNil(t, err)
+	expect(t, res.Code, http.StatusOK)
+	expect(t, res.Body.String(), "<h2>Latest posts</h2><ul><li>hello</li></ul>")
+}
+
+func TestFragmentNotInPage(t *testing.T) {
+	render := New(Options{
+		Directory: "fixtures/fragments",
+	})
+
+	res := httptest.NewRecorder()
+	err := render.Fragment(res, http.StatusOK, "users", "stray", nil)
+
+	expect(t, errors.Is(err, ErrFragmentNotFound), true)
+	expect(t, err.Error(), `render: fragment not found: "stray" is not defined in "users"`)
+	expect(t, res.Code, http.StatusInternalServerError)
+
+	res = httptest.NewRecorder()
+	err = render.Fragment(res, http.StatusOK, "users", "missing", nil)
+
+	expect(t, errors.Is(err, ErrFragmentNotFound), true)
+	expect(t, res.Code, http.StatusInternalServerError)
+}
+
+func TestFragmentNotFoundErrorHandler(t *testing.T) {
+	var render *Render
+	var handled *RenderError
+	render = New(Options{
+		Directory: "fixtures/fragments",
+		ErrorHandler: func(w io.Writer, e *RenderError) error {
+			handled = e
+			return render.Text(w, http.StatusNotFound, "no such fragment")
+		},
+	})
+
+	res := httptest.NewRecorder()
+	err := render.Fragment(res, http.StatusOK, "users", "missing", nil)
+
+	expectNil(t, err)
+	expectNotNil(t, handled)
+	expect(t, errors.Is(handled, ErrFragmentNotFound), true)
+	expect(t, handled.Status, http.StatusInternalServerError)
+	expect(t, res.Code, http.StatusNotFound)
+	expect(t, res.Body.String(), "no such fragment")
+}
+
+func TestFragmentPageNotFound(t *testing.T) {
+	render := New(Options{
+		Directory: "fixtures/fragments",
+	})
+
+	res := httptest.NewRecorder()
+	err := render.Fragment(res, http.StatusOK, "nope", "list", nil)
+
+	expectNotNil(t, err)
+	expect(t, errors.Is(err, ErrFragmentNotFound), false)
+	expect(t, res.Code, http.StatusInternalServerError)
+}
+
+func TestFragmentRequest(t *testing.T) {
+	render := New(Options{
+		Directory: "fixtures/fragments",
+		Layout:    "layout",
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	err := render.FragmentRequest(res, req, http.StatusOK, "users", "list", []string{"alice"})
+
+	expectNil(t, err)
+	expect(t, res.Header().Get("Vary"), "HX-Request")
+	expect(t, res.Body.String(), "<html><h1>Users</h1>\n<ul><li>alice</li></ul>\n</html>\n")
+
+	res = httptest.NewRecorder()
+	req.Header.Set("HX-Request", "true")
+	err = render.FragmentRequest(res, req, http.StatusOK, "users", "list", []string{"alice"})
+
+	expectNil(t, err)
+	expect(t, res.Header().Get("Vary"), "HX-Request")
+	expect(t, res.Body.String(), "<ul><li>alice</li></ul>")
+
+	res = httptest.NewRecorder()
+	req.Header.Set("HX-Boosted", "true")
+	err = render.FragmentRequest(res, req, http.StatusOK, "users", "list", []string{"alice"})
+
+	expectNil(t, err)
+	expect(t, res.Body.String(), "<html><h1>Users</h1>\n<ul><li>alice</li></ul>\n</html>\n")
+}
+
+func TestIsFragmentRequest(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	expect(t, IsFragmentRequest(req), false)
+
+	req.Header.Set("HX-Request", "true")
+	expect(t, IsFragmentRequest(req), true)
+
+	req.Header.Set("HX-Boosted", "true")
+	expect(t, IsFragmentRequest(req), false)
+}
+
+func TestFragmentWithBufferPool(t *testing.T) {
+	render := New(Options{
+		Directory:  "fixtures/fragments",
+		BufferPool: NewSizedBufferPool(1, 64),
+	})
+
+	for i := 0; i < 3; i++ {
+		res := httptest.NewRecorder()
+		err := render.Fragment(res, http.StatusOK, "users", "list", []string{"alice"})
+
+		expectNil(t, err)
+		expect(t, res.Body.String(), "<ul><li>alice</li></ul>")
+	}
+}
@@ -0,0 +1,113 @@
+package render
+
+import (
+	"embed"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+//go:embed fixtures/basic
+var embedFixtures embed.FS
+
+func TestHTMLLoadFromEmbedFS(t *testing.T) {
+	render := New(Options{
+		FS:        embedFixtures,
+		Directory: "fixtures/basic",
+		Layout:    "layout",
+	})
+
+	var err error
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err = render.HTML(w, http.StatusOK, "content", "gophers")
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	h.ServeHTTP(res, req)
+
+	expectNil(t, err)
+	expect(t, res.Code, 200)
+	expect(t, res.Header().Get(ContentType), ContentHTML+"; charset=UTF-8")
+	expect(t, res.Body.String(), "head\n<h1>gophers</h1>\n\nfoot\n")
+	expect(t, render.TemplateLookup("admin/index") != nil, true)
+}
+
+func TestHTMLLoadFromSubFS(t *testing.T) {
+	sub, err := fs.Sub(embedFixtures, "fixtures/basic")
+	expectNil(t, err)
+
+	render := New(Options{
+		FS: sub,
+	})
+
+	res := httptest.NewRecorder()
+	err = render.HTML(res, http.StatusOK, "admin/index", "gophers")
+
+	expectNil(t, err)
+	expect(t, res.Body.String(), "<h1>Admin gophers</h1>\n")
+}
+
+func TestHTMLLoadFromMapFS(t *testing.T) {
+	render := New(Options{
+		FS: fstest.MapFS{
+			"test.tmpl":     {Data: []byte("<h1>{{ . }}</h1>")},
+			"layout.tmpl":   {Data: []byte("head {{ yield }} foot")},
+			"ignored.html":  {Data: []byte("{{ broken")},
+			"nested/a.tmpl": {Data: []byte("a")},
+		},
+		Layout: "layout",
+	})
+
+	res := httptest.NewRecorder()
+	err := render.HTML(res, http.StatusOK, "test", "gophers")
+
+	expectNil(t, err)
+	expect(t, res.Body.String(), "head <h1>gophers</h1> foot")
+	expect(t, render.TemplateLookup("nested/a") != nil, true)
+	expect(t, render.TemplateLookup("ignored") == nil, true)
+}
+
+func TestMapFSCompileError(t *testing.T) {
+	_, err := NewWithError(Options{
+		FS: fstest.MapFS{
+			"templates/bad.tmpl": {Data: []byte("line one\n{{ broken")},
+		},
+		Directory: "templates",
+	})
+
+	var cerr *CompileError
+	expect(t, errors.As(err, &cerr), true)
+	expect(t, cerr.Errors[0].Path, "templates/bad.tmpl")
+	expect(t, cerr.Errors[0].Line, 2)
+}
+
+func TestMultiPartExtensionsFromDir(t *testing.T) {
+	render := New(Options{
+		Directory:  "fixtures/multi_ext",
+		Extensions: []string{".tmpl", ".html.tmpl"},
+	})
+
+	expect(t, render.TemplateLookup("page") != nil, true)
+	expect(t, render.TemplateLookup("page.html") == nil, true)
+	expect(t, render.TemplateLookup("plain") != nil, true)
+}
+
+func TestMultiPartExtensionsFromAsset(t *testing.T) {
+	render := New(Options{
+		Asset: func(file string) ([]byte, error) {
+			return []byte("{{ . }}"), nil
+		},
+		AssetNames: func() []string {
+			return []string{"templates/page.html.tmpl", "templates/plain.tmpl", "templates/dotted.name.tmpl"}
+		},
+		Extensions: []string{".tmpl", ".html.tmpl"},
+	})
+
+	expect(t, render.TemplateLookup("page") != nil, true)
+	expect(t, render.TemplateLookup("plain") != nil, true)
+	expect(t, render.TemplateLookup("dotted.name") != nil, true)
+}
@@ -0,0 +1,172 @@
+package render
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHeadWriteHeadersAndCookies(t *testing.T) {
+	res := httptest.NewRecorder()
+	res.Header().Set("Cache-Control", "no-cache")
+	res.Header().Set("X-Existing", "kept")
+
+	h := Head{
+		ContentType: ContentJSON,
+		Status:      http.StatusCreated,
+		Headers: http.Header{
+			"Cache-Control": {"max-age=60"},
+			"Link":          {"</style.css>; rel=preload", "</app.js>; rel=preload"},
+		},
+		Cookies: []*http.Cookie{
+			{Name: "session", Value: "abc", HttpOnly: true},
+		},
+	}
+	h.Write(res)
+
+	expect(t, res.Code, http.StatusCreated)
+	expect(t, res.Header().Get(ContentType), ContentJSON)
+	expect(t, res.Header().Get("Cache-Control"), "max-age=60")
+	expect(t, res.Header().Get("X-Existing"), "kept")
+	expect(t, len(res.Header()["Link"]), 2)
+	expect(t, res.Header().Get("Set-Cookie"), "session=abc; HttpOnly")
+}
+
+func TestHTMLHeaders(t *testing.T) {
+	render := New(Options{
+		Directory: "fixtures/basic",
+	})
+
+	res := httptest.NewRecorder()
+	err := render.HTML(res, http.StatusFound, "hello", "gophers", HTMLOptions{
+		Headers: http.Header{"Location": {"/bar"}},
+		Cookies: []*http.Cookie{{Name: "flash", Value: "saved"}},
+	})
+
+	expectNil(t, err)
+	expect(t, res.Code, http.StatusFound)
+	expect(t, res.Header().Get("Location"), "/bar")
+	expect(t, res.Header().Get("Set-Cookie"), "flash=saved")
+}
+
+func TestRequestVariantHeaders(t *testing.T) {
+	render := New()
+	opt := RequestOptions{
+		Headers: http.Header{"Cache-Control": {"max-age=60"}},
+		Cookies: []*http.Cookie{{Name: "flash", Value: "saved"}},
+	}
+
+	renders := map[string]func(w http.ResponseWriter, req *http.Request) error{
+		"Data": func(w http.ResponseWriter, req *http.Request) error {
+			return render.DataRequest(w, req, http.StatusOK, []byte("hello"), opt)
+		},
+		"JSON": func(w http.ResponseWriter, req *http.Request) error {
+			return render.JSONRequest(w, req, http.StatusOK, Greeting{"hello", "world"}, opt)
+		},
+		"JSONP": func(w http.ResponseWriter, req *http.Request) error {
+			return render.JSONPRequest(w, req, http.StatusOK, "cb", Greeting{"hello", "world"}, opt)
+		},
+		"Text": func(w http.ResponseWriter, req *http.Request) error {
+			return render.TextRequest(w, req, http.StatusOK, "hello", opt)
+		},
+		"XML": func(w http.ResponseWriter, req *http.Request) error {
+			return render.XMLRequest(w, req, http.StatusOK, GreetingXML{One: "hello", Two: "world"}, opt)
+		},
+		"YAML": func(w http.ResponseWriter, req *http.Request) error {
+			return render.YAMLRequest(w, req, http.StatusOK, Greeting{"hello", "world"}, opt)
+		},
+		"MsgPack": func(w http.ResponseWriter, req *http.Request) error {
+			return render.MsgPackRequest(w, req, http.StatusOK, Greeting{"hello", "world"}, opt)
+		},
+		"CBOR": func(w http.ResponseWriter, req *http.Request) error {
+			return render.CBORRequest(w, req, http.StatusOK, Greeting{"hello", "world"}, opt)
+		},
+	}
+
+	for name, fn := range renders {
+		req, _ := http.NewRequest("GET", "/foo", nil)
+		res := httptest.NewRecorder()
+		err := fn(res, req)
+
+		expectNil(t, err)
+		expect(t, res.Header().Get("Cache-Control")+" "+name, "max-age=60 "+name)
+		expect(t, res.Header().Get("Set-Cookie")+" "+name, "flash=saved "+name)
+	}
+}
+
+func TestRequestHeaders(t *testing.T) {
+	render := New()
+
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	res := httptest.NewRecorder()
+	err := render.JSONRequest(res, req, http.StatusOK, Greeting{"hello", "world"}, RequestOptions{
+		Headers: http.Header{"Cache-Control": {"no-store"}},
+	})
+
+	expectNil(t, err)
+	expect(t, res.Header().Get("Cache-Control"), "no-store")
+	expect(t, res.Header().Get(ContentType), ContentJSON+"; charset=UTF-8")
+}
+
+func TestHeadersNotSentOnError(t *testing.T) {
+	render := New()
+
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	res := httptest.NewRecorder()
+	err := render.JSONRequest(res, req, http.StatusOK, func() {}, RequestOptions{
+		Headers: http.Header{"Cache-Control": {"max-age=3600"}},
+		Cookies: []*http.Cookie{{Name: "session", Value: "abc"}},
+	})
+
+	expectNotNil(t, err)
+	expect(t, res.Code, http.StatusInternalServerError)
+	expect(t, res.Header().Get("Cache-Control"), "")
+	expect(t, res.Header().Get("Set-Cookie"), "")
+}
+
+func TestDefaultHeaders(t *testing.T) {
+	render := New(Options{
+		Headers: http.Header{
+			"X-Frame-Options": {"DENY"},
+			"Cache-Control":   {"no-cache"},
+		},
+	})
+
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	res := httptest.NewRecorder()
+	err := render.TextRequest(res, req, http.StatusOK, "Hello Text!", RequestOptions{
+		Headers: http.Header{"Cache-Control": {"max-age=60"}},
+	})
+
+	expectNil(t, err)
+	expect(t, res.Header().Get("X-Frame-Options"), "DENY")
+	expect(t, res.Header().Get("Cache-Control"), "max-age=60")
+	expect(t, res.Body.String(), "Hello Text!")
+}
+
+func TestDefaultHeadersDoNotOverride(t *testing.T) {
+	render := New(Options{
+		Headers: http.Header{"X-Frame-Options": {"DENY"}},
+	})
+
+	res := httptest.NewRecorder()
+	res.Header().Set("X-Frame-Options", "SAMEORIGIN")
+	err := render.Data(res, http.StatusOK, []byte("hello"))
+
+	expectNil(t, err)
+	expect(t, res.Header().Get("X-Frame-Options"), "SAMEORIGIN")
+	expect(t, res.Body.String(), "hello")
+}
+
+func TestDefaultHeadersCustomEngine(t *testing.T) {
+	render := New(Options{
+		Headers: http.Header{"X-Frame-Options": {"DENY"}},
+	})
+
+	res := httptest.NewRecorder()
+	err := render.Render(res, CSVGreeting{Head: Head{ContentType: "text/csv", Status: http.StatusOK}}, Greeting{"hello", "world"})
+
+	expectNil(t, err)
+	expect(t, res.Header().Get("X-Frame-Options"), "DENY")
+	expect(t, res.Body.String(), "one,two\nhello,world\n")
+}
@@ -0,0 +1,108 @@
+package render
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// flushRecorder calls onFlush with the body sent so far on every flush.
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	onFlush func(body string)
+}
+
+func (f *flushRecorder) Flush() {
+	f.ResponseRecorder.Flush()
+	f.onFlush(f.Body.String())
+}
+
+func deferredFuncs(wait func() string) []template.FuncMap {
+	return []template.FuncMap{
+		{
+			"wait": wait,
+			"fail": func() (string, error) {
+				return "", errFail
+			},
+		},
+	}
+}
+
+func TestHTMLDeferredPartial(t *testing.T) {
+	render := New(Options{
+		Directory: "fixtures/deferred",
+		Layout:    "layout",
+		Funcs:     deferredFuncs(func() string { return "" }),
+	})
+
+	res := httptest.NewRecorder()
+	err := render.HTML(res, http.StatusOK, "page", "gophers")
+
+	expectNil(t, err)
+	expect(t, res.Code, http.StatusOK)
+	expect(t, res.Body.String(), `<main><h1>gophers</h1><div id="render-deferred-0"></div><p>after</p>
+
+</main>
+<template id="render-deferred-0-content"><aside>slow gophers</aside></template>`+
+		`<script>(function(){var t=document.getElementById("render-deferred-0-content"),p=document.getElementById("render-deferred-0");if(t&&p){p.replaceWith(t.content);t.remove()}})()</script>`)
+}
+
+func TestHTMLDeferredPartialDoesNotBlockStreaming(t *testing.T) {
+	release := make(chan struct{})
+	render := New(Options{
+		Directory:     "fixtures/deferred",
+		Layout:        "layout",
+		StreamingHTML: true,
+		Funcs: deferredFuncs(func() string {
+			<-release
+			return ""
+		}),
+	})
+
+	var sentBeforePartial string
+	res := &flushRecorder{
+		ResponseRecorder: httptest.NewRecorder(),
+		onFlush: func(body string) {
+			if strings.Contains(body, "</main>") && sentBeforePartial == "" {
+				sentBeforePartial = body
+				close(release)
+			}
+		},
+	}
+
+	err := render.HTML(res, http.StatusOK, "page", "gophers")
+
+	expectNil(t, err)
+	expect(t, sentBeforePartial, "<main><h1>gophers</h1><div id=\"render-deferred-0\"></div><p>after</p>\n\n</main>\n")
+	expect(t, strings.HasSuffix(res.Body.String(), "</script>"), true)
+	expect(t, strings.Contains(res.Body.String(), "<aside>slow gophers</aside>"), true)
+}
+
+func TestHTMLDeferredPartialError(t *testing.T) {
+	render := New(Options{
+		Directory: "fixtures/deferred",
+		Layout:    "layout",
+		Funcs:     deferredFuncs(func() string { return "" }),
+	})
+
+	res := httptest.NewRecorder()
+	err := render.HTML(res, http.StatusOK, "broken", "gophers")
+
+	expectNotNil(t, err)
+	expect(t, res.Code, http.StatusInternalServerError)
+}
+
+func TestHTMLDeferredPartialWithoutLayout(t *testing.T) {
+	render := New(Options{
+		Directory: "fixtures/deferred",
+		Funcs:     deferredFuncs(func() string { return "" }),
+	})
+
+	res := httptest.NewRecorder()
+	err := render.HTML(res, http.StatusOK, "page", "gophers")
+
+	expectNotNil(t, err)
+	expect(t, res.Code, http.StatusInternalServerError)
+}
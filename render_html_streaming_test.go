@@ -0,0 +1,168 @@
+package render
+
+import (
+	"errors"
+	"html/template"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var streamingFuncs = []template.FuncMap{
+	{
+		"snapshot": func() string {
+			return ""
+		},
+		"fail": func() (string, error) {
+			return "", errFail
+		},
+	},
+}
+
+func TestHTMLStreamingFlushesBeforeYield(t *testing.T) {
+	render := New(Options{
+		Directory:     "fixtures/streaming",
+		Layout:        "layout",
+		Funcs:         streamingFuncs,
+		StreamingHTML: true,
+	})
+
+	res := httptest.NewRecorder()
+	var sentBeforeYield string
+	var flushedBeforeYield bool
+	err := render.HTML(res, http.StatusAccepted, "page", "gophers", HTMLOptions{
+		Funcs: template.FuncMap{
+			"snapshot": func() string {
+				sentBeforeYield = res.Body.String()
+				flushedBeforeYield = res.Flushed
+				return ""
+			},
+		},
+	})
+
+	expectNil(t, err)
+	expect(t, flushedBeforeYield, true)
+	expect(t, sentBeforeYield, "<head></head>\n<body>")
+	expect(t, res.Code, http.StatusAccepted)
+	expect(t, res.Header().Get(ContentType), ContentHTML+"; charset=UTF-8")
+	expect(t, res.Body.String(), "<head></head>\n<body><p>gophers</p></body>\n")
+}
+
+func TestHTMLStreamingPerCall(t *testing.T) {
+	render := New(Options{
+		Directory: "fixtures/streaming",
+		Layout:    "layout",
+		Funcs:     streamingFuncs,
+	})
+
+	res := httptest.NewRecorder()
+	err := render.HTML(res, http.StatusOK, "page", "gophers")
+
+	expectNil(t, err)
+	expect(t, res.Flushed, false)
+
+	res = httptest.NewRecorder()
+	err = render.HTML(res, http.StatusOK, "page", "gophers", HTMLOptions{
+		Streaming: true,
+	})
+
+	expectNil(t, err)
+	expect(t, res.Flushed, true)
+	expect(t, res.Body.String(), "<head></head>\n<body><p>gophers</p></body>\n")
+}
+
+func TestHTMLStreamingWithoutLayout(t *testing.T) {
+	render := New(Options{
+		Directory:     "fixtures/basic",
+		StreamingHTML: true,
+	})
+
+	res := httptest.NewRecorder()
+	err := render.HTML(res, http.StatusOK, "hello", "gophers")
+
+	expectNil(t, err)
+	expect(t, res.Code, http.StatusOK)
+	expect(t, res.Body.String(), "<h1>Hello gophers</h1>\n")
+}
+
+func TestHTMLStreamingErrorBeforeFlush(t *testing.T) {
+	render := New(Options{
+		Directory:     "fixtures/streaming",
+		Layout:        "broken_layout",
+		Funcs:         streamingFuncs,
+		StreamingHTML: true,
+	})
+
+	res := httptest.NewRecorder()
+	err := render.HTML(res, http.StatusOK, "page", "gophers")
+
+	expectNotNil(t, err)
+	expect(t, res.Code, http.StatusInternalServerError)
+	expect(t, res.Header().Get(ContentType), "text/plain; charset=utf-8")
+}
+
+func TestHTMLStreamingErrorAfterFlush(t *testing.T) {
+	var handled *RenderError
+	render := New(Options{
+		Directory:     "fixtures/streaming",
+		Layout:        "layout",
+		Funcs:         streamingFuncs,
+		StreamingHTML: true,
+		ErrorHandler: func(w io.Writer, e *RenderError) error {
+			handled = e
+			return e.Err
+		},
+	})
+
+	res := httptest.NewRecorder()
+	err := render.HTML(res, http.StatusOK, "broken", "gophers")
+
+	expectNotNil(t, err)
+	expectNotNil(t, handled)
+	expect(t, handled.HeadersWritten, true)
+	expect(t, res.Code, http.StatusOK)
+	expect(t, res.Body.String(), "<head></head>\n<body>")
+}
+
+func TestHTMLStreamingErrorAfterFlushWithoutErrorHandler(t *testing.T) {
+	render := New(Options{
+		Directory:     "fixtures/streaming",
+		Layout:        "layout",
+		Funcs:         streamingFuncs,
+		StreamingHTML: true,
+	})
+
+	res := httptest.NewRecorder()
+	err := render.HTML(res, http.StatusOK, "broken", "gophers")
+
+	var streamErr *StreamError
+	expect(t, errors.As(err, &streamErr), true)
+	expect(t, errors.Is(err, errFail), true)
+	expect(t, res.Code, http.StatusOK)
+	expect(t, res.Body.String(), "<head></head>\n<body>")
+}
+
+func TestHTMLStreamingFlushWritesHead(t *testing.T) {
+	render := New(Options{
+		Directory:     "fixtures/streaming",
+		Layout:        "yield_layout",
+		Funcs:         streamingFuncs,
+		StreamingHTML: true,
+	})
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		render.HTML(w, http.StatusNotFound, "page", "gophers")
+	}))
+	defer s.Close()
+
+	res, err := http.Get(s.URL)
+	expectNil(t, err)
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+
+	expectNil(t, err)
+	expect(t, res.StatusCode, http.StatusNotFound)
+	expect(t, res.Header.Get(ContentType), ContentHTML+"; charset=UTF-8")
+	expect(t, string(body), "<p>gophers</p>\n")
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"testing/fstest"
 )
 
 func TestHTMLBad(t *testing.T) {
@@ -167,6 +168,62 @@ func TestHTMLFuncs(t *testing.T) {
 	expect(t, res.Body.String(), "My custom function\n")
 }
 
+func TestHTMLFuncsOverrideHelpers(t *testing.T) {
+	tests := []struct {
+		helper   string
+		template string
+		funcs    template.FuncMap
+		expected string
+	}{
+		// current was built in before the other helpers, so it is not overridden.
+		{"current", `{{ current }}`, template.FuncMap{
+			"current": func() string { return "overridden" },
+		}, ""},
+		{"deferred", `{{ deferred "x" }}`, template.FuncMap{
+			"deferred": func(name string) string { return "deferred " + name },
+		}, "deferred x"},
+		{"request", `{{ request }}`, template.FuncMap{
+			"request": func() string { return "request" },
+		}, "request"},
+		{"requestData", `{{ requestData "x" }}`, template.FuncMap{
+			"requestData": func(key string) string { return "data " + key },
+		}, "data x"},
+		{"layout", `{{ layout "base" }}`, template.FuncMap{
+			"layout": func(name string) string { return "layout " + name },
+		}, "layout base"},
+		{"section", `{{ section "title" "Home" }}`, template.FuncMap{
+			"section": func(name, title string) string { return name + "=" + title },
+		}, "title=Home"},
+		{"partialWith", `{{ partialWith "card" "x" }}`, template.FuncMap{
+			"partialWith": func(name, data string) string { return name + " " + data },
+		}, "card x"},
+		{"dict", `{{ dict "k" }}`, template.FuncMap{
+			"dict": func(key string) string { return "dict " + key },
+		}, "dict k"},
+		{"component", `{{ component "card" }}`, template.FuncMap{
+			"component": func(name string) string { return "component " + name },
+		}, "component card"},
+		{"props", `{{ props "a" }}`, template.FuncMap{
+			"props": func(name string) string { return "props " + name },
+		}, "props a"},
+	}
+
+	for _, test := range tests {
+		render := New(Options{
+			FS:               fstest.MapFS{"index.tmpl": {Data: []byte(test.template)}},
+			StrictComponents: true,
+			Funcs:            []template.FuncMap{test.funcs},
+		})
+
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/foo", nil)
+		err := render.HTMLRequest(res, req, http.StatusOK, "index", nil)
+
+		expectNil(t, err)
+		expect(t, test.helper+": "+res.Body.String(), test.helper+": "+test.expected)
+	}
+}
+
 func TestRenderLayout(t *testing.T) {
 	render := New(Options{
 		Directory: "fixtures/basic",
@@ -387,7 +444,7 @@ func TestCompileTemplatesFromDir(t *testing.T) {
 		Directory:  baseDir,
 		Extensions: []string{".tmpl", ".html"},
 	})
-	r.compileTemplatesFromDir()
+	expectNil(t, r.Recompile())
 
 	expect(t, r.TemplateLookup(fname1Rel) != nil, true)
 	expect(t, r.TemplateLookup(fname0Rel) != nil, true)
@@ -415,3 +472,114 @@ func TestHTMLDisabledCharset(t *testing.T) {
 	expect(t, res.Header().Get(ContentType), ContentHTML)
 	expect(t, res.Body.String(), "<h1>Hello gophers</h1>\n")
 }
+
+func TestHTMLLayoutFuncsNoRace(t *testing.T) {
+	render := New(Options{
+		Directory: "fixtures/basic",
+		Layout:    "current_layout",
+	})
+
+	done := make(chan bool)
+	doreq := func(name, binding string, layout string, expected string) {
+		res := httptest.NewRecorder()
+		err := render.HTML(res, http.StatusOK, name, binding, HTMLOptions{
+			Layout: layout,
+		})
+
+		expectNil(t, err)
+		expect(t, res.Body.String(), expected)
+		done <- true
+	}
+
+	for i := 0; i < 50; i++ {
+		go doreq("content", "gophers", "", "content head\n<h1>gophers</h1>\n\ncontent foot\n")
+		go doreq("admin/index", "admins", "", "admin/index head\n<h1>Admin admins</h1>\n\nadmin/index foot\n")
+		go doreq("content", "gophers", "another_layout", "another head\n<h1>gophers</h1>\n\nanother foot\n")
+	}
+	for i := 0; i < 150; i++ {
+		<-done
+	}
+}
+
+func TestHTMLFuncsDoNotLeak(t *testing.T) {
+	render := New(Options{
+		Directory: "fixtures/custom_funcs",
+		Funcs: []template.FuncMap{
+			{
+				"myCustomFunc": func() string {
+					return "My custom function"
+				},
+			},
+		},
+	})
+
+	res := httptest.NewRecorder()
+	err := render.HTML(res, http.StatusOK, "index", "gophers", HTMLOptions{
+		Funcs: template.FuncMap{
+			"myCustomFunc": func() string {
+				return "My overridden function"
+			},
+		},
+	})
+
+	expectNil(t, err)
+	expect(t, res.Body.String(), "My overridden function\n")
+
+	res = httptest.NewRecorder()
+	err = render.HTML(res, http.StatusOK, "index", "gophers")
+
+	expectNil(t, err)
+	expect(t, res.Body.String(), "My custom function\n")
+}
+
+func TestHTMLYieldWithoutLayout(t *testing.T) {
+	render := New(Options{
+		Directory: "fixtures/basic",
+	})
+
+	res := httptest.NewRecorder()
+	err := render.HTML(res, http.StatusOK, "content", "gophers", HTMLOptions{
+		Layout: "layout",
+	})
+	expectNil(t, err)
+	expect(t, res.Body.String(), "head\n<h1>gophers</h1>\n\nfoot\n")
+
+	// The layout funcs of the previous render must not leak into this one.
+	res = httptest.NewRecorder()
+	err = render.HTML(res, http.StatusOK, "layout", "gophers")
+	expectNotNil(t, err)
+	expect(t, res.Code, 500)
+}
+
+func BenchmarkHTMLParallel(b *testing.B) {
+	b.ReportAllocs()
+
+	render := New(Options{
+		Directory: "fixtures/basic",
+	})
+
+	b.RunParallel(func(pb *testing.PB) {
+		var buf = new(bytes.Buffer)
+		for pb.Next() {
+			render.HTML(buf, http.StatusOK, "hello", "gophers")
+			buf.Reset()
+		}
+	})
+}
+
+func BenchmarkHTMLLayoutParallel(b *testing.B) {
+	b.ReportAllocs()
+
+	render := New(Options{
+		Directory: "fixtures/basic",
+		Layout:    "layout",
+	})
+
+	b.RunParallel(func(pb *testing.PB) {
+		var buf = new(bytes.Buffer)
+		for pb.Next() {
+			render.HTML(buf, http.StatusOK, "content", "gophers")
+			buf.Reset()
+		}
+	})
+}
@@ -0,0 +1,160 @@
+package render
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestHTMLLayoutChain(t *testing.T) {
+	render := New(Options{
+		Directory: "fixtures/layouts",
+		Layout:    "settings",
+	})
+
+	var err error
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err = render.HTML(w, http.StatusOK, "page", "gophers")
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	h.ServeHTTP(res, req)
+
+	expectNil(t, err)
+	expect(t, res.Body.String(), "<base>Settings\n<admin>page\n<settings>\n<p>gophers</p>\n</settings>\n</admin>\n</base>\n")
+}
+
+func TestHTMLLayoutChainOverride(t *testing.T) {
+	render := New(Options{
+		Directory: "fixtures/layouts",
+		Layout:    "settings",
+	})
+
+	res := httptest.NewRecorder()
+	err := render.HTML(res, http.StatusOK, "page", "gophers", HTMLOptions{Layout: "admin"})
+
+	expectNil(t, err)
+	expect(t, res.Body.String(), "<base>Settings\n<admin>page\n<p>gophers</p>\n</admin>\n</base>\n")
+
+	res = httptest.NewRecorder()
+	err = render.HTML(res, http.StatusOK, "page", "gophers", HTMLOptions{Layout: "base"})
+
+	expectNil(t, err)
+	expect(t, res.Body.String(), "<base>Settings\n<p>gophers</p>\n</base>\n")
+}
+
+func TestHTMLLayoutChainStreaming(t *testing.T) {
+	render := New(Options{
+		Directory:     "fixtures/layouts",
+		Layout:        "settings",
+		StreamingHTML: true,
+	})
+
+	res := httptest.NewRecorder()
+	err := render.HTML(res, http.StatusOK, "page", "gophers")
+
+	expectNil(t, err)
+	expect(t, res.Flushed, true)
+	expect(t, res.Body.String(), "<base>Settings\n<admin>page\n<settings>\n<p>gophers</p>\n</settings>\n</admin>\n</base>\n")
+}
+
+func TestHTMLLayoutChainWithoutLayout(t *testing.T) {
+	render := New(Options{
+		Directory: "fixtures/layouts",
+	})
+
+	res := httptest.NewRecorder()
+	err := render.HTML(res, http.StatusOK, "page", "gophers")
+
+	expectNil(t, err)
+	expect(t, res.Body.String(), "<p>gophers</p>\n")
+}
+
+func TestHTMLYieldFromPage(t *testing.T) {
+	render := New(Options{
+		FS: fstest.MapFS{
+			"layout.tmpl": {Data: []byte("<main>{{ yield }}</main>")},
+			"page.tmpl":   {Data: []byte("{{ yield }}")},
+		},
+		Layout: "layout",
+	})
+
+	res := httptest.NewRecorder()
+	err := render.HTML(res, http.StatusOK, "page", nil)
+
+	expectNotNil(t, err)
+	expect(t, res.Code, http.StatusInternalServerError)
+}
+
+func TestLayoutCycle(t *testing.T) {
+	_, err := NewWithError(Options{
+		FS: fstest.MapFS{
+			"a.tmpl":    {Data: []byte(`{{ layout "b" }}{{ yield }}`)},
+			"b.tmpl":    {Data: []byte(`{{ layout "c" }}{{ yield }}`)},
+			"c.tmpl":    {Data: []byte(`{{ layout "a" }}{{ yield }}`)},
+			"page.tmpl": {Data: []byte(`page`)},
+		},
+	})
+
+	cerr, ok := err.(*CompileError)
+	expect(t, ok, true)
+	expect(t, len(cerr.Errors), 3)
+	expect(t, cerr.Errors[0].Path, "a.tmpl")
+	expect(t, cerr.Errors[0].Message, "layout chain forms a cycle: a -> b -> c -> a")
+	expect(t, cerr.Errors[2].Path, "c.tmpl")
+	expect(t, cerr.Errors[2].Message, "layout chain forms a cycle: c -> a -> b -> c")
+}
+
+func TestLayoutSelfCycle(t *testing.T) {
+	_, err := NewWithError(Options{
+		FS: fstest.MapFS{
+			"layout.tmpl": {Data: []byte(`{{ layout "layout" }}{{ yield }}`)},
+		},
+	})
+
+	cerr, ok := err.(*CompileError)
+	expect(t, ok, true)
+	expect(t, len(cerr.Errors), 1)
+	expect(t, cerr.Errors[0].Message, "layout chain forms a cycle: layout -> layout")
+}
+
+func TestLayoutMissingParent(t *testing.T) {
+	_, err := NewWithError(Options{
+		FS: fstest.MapFS{
+			"admin.tmpl": {Data: []byte(`{{ layout "base" }}{{ yield }}`)},
+		},
+	})
+
+	cerr, ok := err.(*CompileError)
+	expect(t, ok, true)
+	expect(t, len(cerr.Errors), 1)
+	expect(t, cerr.Errors[0].Path, "admin.tmpl")
+	expect(t, cerr.Errors[0].Message, `parent layout "base" of "admin" is not defined`)
+}
+
+func TestReloadLayoutCycle(t *testing.T) {
+	fsys := fstest.MapFS{
+		"base.tmpl":  {Data: []byte(`<base>{{ yield }}</base>`)},
+		"admin.tmpl": {Data: []byte(`{{ layout "base" }}<admin>{{ yield }}</admin>`)},
+		"page.tmpl":  {Data: []byte(`page`)},
+	}
+	render := New(Options{
+		FS:     fsys,
+		Layout: "admin",
+	})
+
+	fsys["base.tmpl"] = &fstest.MapFile{Data: []byte(`{{ layout "admin" }}<base>{{ yield }}</base>`)}
+	err := render.Reload()
+
+	_, ok := err.(*CompileError)
+	expect(t, ok, true)
+
+	// The previous templates are kept.
+	res := httptest.NewRecorder()
+	err = render.HTML(res, http.StatusOK, "page", nil)
+
+	expectNil(t, err)
+	expect(t, res.Body.String(), "<base><admin>page</admin></base>")
+}
@@ -0,0 +1,162 @@
+package render
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNDJSONSlice(t *testing.T) {
+	render := New()
+
+	var err error
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err = render.NDJSON(w, http.StatusOK, []Greeting{{"hello", "world"}, {"<b>", "&"}})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	h.ServeHTTP(res, req)
+
+	expectNil(t, err)
+	expect(t, res.Code, http.StatusOK)
+	expect(t, res.Header().Get(ContentType), ContentNDJSON+"; charset=UTF-8")
+	expect(t, res.Flushed, true)
+	expect(t, res.Body.String(), "{\"one\":\"hello\",\"two\":\"world\"}\n{\"one\":\"\\u003cb\\u003e\",\"two\":\"\\u0026\"}\n")
+}
+
+func TestNDJSONUnEscapeHTML(t *testing.T) {
+	render := New(Options{
+		UnEscapeHTML: true,
+	})
+
+	res := httptest.NewRecorder()
+	err := render.NDJSON(res, http.StatusOK, []Greeting{{"<b>", "&"}})
+
+	expectNil(t, err)
+	expect(t, res.Body.String(), "{\"one\":\"<b>\",\"two\":\"&\"}\n")
+}
+
+func TestNDJSONChannel(t *testing.T) {
+	render := New()
+
+	ch := make(chan int)
+	go func() {
+		for i := 1; i <= 3; i++ {
+			ch <- i
+		}
+		close(ch)
+	}()
+
+	res := httptest.NewRecorder()
+	err := render.NDJSON(res, http.StatusOK, ch)
+
+	expectNil(t, err)
+	expect(t, res.Body.String(), "1\n2\n3\n")
+}
+
+func TestNDJSONIterator(t *testing.T) {
+	render := New()
+
+	it := func(yield func(v interface{}) error) error {
+		for _, word := range []string{"a", "b"} {
+			if err := yield(map[string]string{"word": word}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	res := httptest.NewRecorder()
+	err := render.NDJSON(res, http.StatusOK, it)
+
+	expectNil(t, err)
+	expect(t, res.Body.String(), "{\"word\":\"a\"}\n{\"word\":\"b\"}\n")
+}
+
+func TestNDJSONMidStreamError(t *testing.T) {
+	render := New()
+
+	res := httptest.NewRecorder()
+	err := render.NDJSON(res, http.StatusOK, []interface{}{1, func() {}, 3})
+
+	var streamErr *StreamError
+	expect(t, errors.As(err, &streamErr), true)
+	expect(t, streamErr.Written, 1)
+	expect(t, res.Code, http.StatusOK)
+	expect(t, res.Body.String(), "1\n")
+}
+
+func TestNDJSONIteratorError(t *testing.T) {
+	render := New()
+	failed := errors.New("database is gone")
+
+	it := Iterator(func(yield func(v interface{}) error) error {
+		if err := yield("first"); err != nil {
+			return err
+		}
+		return failed
+	})
+
+	res := httptest.NewRecorder()
+	err := render.NDJSON(res, http.StatusOK, it)
+
+	expect(t, errors.Is(err, failed), true)
+	expect(t, res.Body.String(), "\"first\"\n")
+}
+
+func TestNDJSONInvalidValue(t *testing.T) {
+	render := New()
+
+	res := httptest.NewRecorder()
+	err := render.NDJSON(res, http.StatusOK, Greeting{"hello", "world"})
+
+	expectNotNil(t, err)
+	expect(t, res.Code, http.StatusInternalServerError)
+}
+
+func TestNDJSONRequestCanceled(t *testing.T) {
+	render := New()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	req = req.WithContext(ctx)
+
+	ch := make(chan string)
+	go func() {
+		ch <- "first"
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	res := httptest.NewRecorder()
+	err := render.NDJSONRequest(res, req, http.StatusOK, ch)
+
+	expect(t, errors.Is(err, context.Canceled), true)
+	expect(t, res.Body.String(), "\"first\"\n")
+}
+
+func TestNDJSONFlushEvery(t *testing.T) {
+	render := New()
+
+	res := &flushCounter{ResponseRecorder: httptest.NewRecorder()}
+	err := render.Render(res, NDJSON{Head: Head{ContentType: ContentNDJSON, Status: http.StatusOK}, FlushEvery: 2}, []int{1, 2, 3, 4, 5})
+
+	expectNil(t, err)
+	// Once for the head, twice for full batches and once for the rest.
+	expect(t, res.flushes, 4)
+	expect(t, res.Body.String(), "1\n2\n3\n4\n5\n")
+}
+
+type flushCounter struct {
+	*httptest.ResponseRecorder
+	flushes int
+}
+
+func (f *flushCounter) Flush() {
+	f.flushes++
+	f.ResponseRecorder.Flush()
+}
@@ -0,0 +1,313 @@
+package render
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type CSVGreeting struct {
+	Head
+}
+
+func (c CSVGreeting) Render(w io.Writer, v interface{}) error {
+	if hw, ok := w.(http.ResponseWriter); ok {
+		c.Head.Write(hw)
+	}
+	g := v.(Greeting)
+	_, err := w.Write([]byte("one,two\n" + g.One + "," + g.Two + "\n"))
+	return err
+}
+
+func TestNegotiateJSON(t *testing.T) {
+	render := New()
+
+	var err error
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err = render.Negotiate(w, r, 299, Greeting{"hello", "world"})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	req.Header.Set("Accept", "text/xml;q=0.5, application/json")
+	h.ServeHTTP(res, req)
+
+	expectNil(t, err)
+	expect(t, res.Code, 299)
+	expect(t, res.Header().Get(ContentType), ContentJSON+"; charset=UTF-8")
+	expect(t, res.Header().Get("Vary"), "Accept")
+	expect(t, res.Body.String(), "{\"one\":\"hello\",\"two\":\"world\"}")
+}
+
+func TestNegotiateXML(t *testing.T) {
+	render := New()
+
+	var err error
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err = render.Negotiate(w, r, 300, GreetingXML{One: "hello", Two: "world"})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	req.Header.Set("Accept", "application/json;q=0.5, text/*")
+	h.ServeHTTP(res, req)
+
+	expectNil(t, err)
+	expect(t, res.Code, 300)
+	expect(t, res.Header().Get(ContentType), ContentXML+"; charset=UTF-8")
+	expect(t, res.Body.String(), "<greeting one=\"hello\" two=\"world\"></greeting>")
+}
+
+func TestNegotiateApplicationXML(t *testing.T) {
+	render := New()
+
+	var err error
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err = render.Negotiate(w, r, http.StatusOK, GreetingXML{One: "hello", Two: "world"})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	req.Header.Set("Accept", "application/xml")
+	h.ServeHTTP(res, req)
+
+	expectNil(t, err)
+	expect(t, res.Code, http.StatusOK)
+	expect(t, res.Header().Get(ContentType), "application/xml; charset=UTF-8")
+	expect(t, res.Body.String(), "<greeting one=\"hello\" two=\"world\"></greeting>")
+}
+
+func TestNegotiateSkipsXMLForMap(t *testing.T) {
+	render := New()
+
+	var err error
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err = render.Negotiate(w, r, http.StatusOK, map[string]string{"hello": "world"})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	req.Header.Set("Accept", "application/json;q=0, */*")
+	h.ServeHTTP(res, req)
+
+	expectNil(t, err)
+	expect(t, res.Code, http.StatusOK)
+	expect(t, res.Header().Get(ContentType), ContentYAML+"; charset=UTF-8")
+	expect(t, res.Body.String(), "hello: world\n")
+
+	res = httptest.NewRecorder()
+	req.Header.Set("Accept", "text/*")
+	h.ServeHTTP(res, req)
+
+	expectNotNil(t, err)
+	expect(t, res.Code, http.StatusNotAcceptable)
+}
+
+func TestNegotiateNoAcceptHeader(t *testing.T) {
+	render := New()
+
+	var err error
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err = render.Negotiate(w, r, http.StatusOK, Greeting{"hello", "world"})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	h.ServeHTTP(res, req)
+
+	expectNil(t, err)
+	expect(t, res.Header().Get(ContentType), ContentJSON+"; charset=UTF-8")
+}
+
+func TestNegotiateHTML(t *testing.T) {
+	render := New(Options{
+		Directory: "fixtures/basic",
+	})
+
+	var err error
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err = render.Negotiate(w, r, http.StatusOK, "gophers", NegotiateOptions{
+			HTMLTemplate: "hello",
+		})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	h.ServeHTTP(res, req)
+
+	expectNil(t, err)
+	expect(t, res.Code, http.StatusOK)
+	expect(t, res.Header().Get(ContentType), ContentHTML+"; charset=UTF-8")
+	expect(t, res.Body.String(), "<h1>Hello gophers</h1>\n")
+}
+
+func TestNegotiateHTMLWithoutTemplate(t *testing.T) {
+	render := New()
+
+	var err error
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err = render.Negotiate(w, r, http.StatusOK, Greeting{"hello", "world"})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	req.Header.Set("Accept", "text/html,*/*;q=0.8")
+	h.ServeHTTP(res, req)
+
+	expectNil(t, err)
+	expect(t, res.Header().Get(ContentType), ContentJSON+"; charset=UTF-8")
+}
+
+func TestNegotiateText(t *testing.T) {
+	render := New()
+
+	var err error
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err = render.Negotiate(w, r, http.StatusOK, "Hello Text!")
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	req.Header.Set("Accept", "text/plain")
+	h.ServeHTTP(res, req)
+
+	expectNil(t, err)
+	expect(t, res.Header().Get(ContentType), ContentText+"; charset=UTF-8")
+	expect(t, res.Body.String(), "Hello Text!")
+}
+
+func TestNegotiateRejectedByZeroQuality(t *testing.T) {
+	render := New()
+
+	var err error
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err = render.Negotiate(w, r, http.StatusOK, Greeting{"hello", "world"})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	req.Header.Set("Accept", "application/json;q=0, */*")
+	h.ServeHTTP(res, req)
+
+	expectNil(t, err)
+	expect(t, res.Header().Get(ContentType), ContentXML+"; charset=UTF-8")
+}
+
+func TestNegotiateNotAcceptable(t *testing.T) {
+	render := New()
+
+	var err error
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err = render.Negotiate(w, r, http.StatusOK, Greeting{"hello", "world"})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	req.Header.Set("Accept", "image/png")
+	h.ServeHTTP(res, req)
+
+	expect(t, err, ErrNotAcceptable)
+	expect(t, res.Code, http.StatusNotAcceptable)
+	expect(t, res.Header().Get("Vary"), "Accept")
+}
+
+func TestNegotiateNotAcceptableErrorHandler(t *testing.T) {
+	var render *Render
+	var handled *RenderError
+	render = New(Options{
+		ErrorHandler: func(w io.Writer, e *RenderError) error {
+			handled = e
+			return render.JSON(w, e.Status, map[string]string{"error": "not_acceptable"})
+		},
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	req.Header.Set("Accept", "image/png")
+	err := render.Negotiate(res, req, http.StatusOK, Greeting{"hello", "world"})
+
+	expectNil(t, err)
+	expectNotNil(t, handled)
+	expect(t, handled.Err, ErrNotAcceptable)
+	expect(t, handled.Status, http.StatusNotAcceptable)
+	expect(t, res.Code, http.StatusNotAcceptable)
+	expect(t, res.Body.String(), `{"error":"not_acceptable"}`)
+}
+
+func TestNegotiateNotAcceptableDisableHTTPErrorRendering(t *testing.T) {
+	render := New(Options{
+		DisableHTTPErrorRendering: true,
+	})
+
+	var err error
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err = render.Negotiate(w, r, http.StatusOK, Greeting{"hello", "world"})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	req.Header.Set("Accept", "image/png")
+	h.ServeHTTP(res, req)
+
+	expect(t, err, ErrNotAcceptable)
+	expect(t, res.Code, http.StatusOK)
+	expect(t, res.Body.String(), "")
+}
+
+func TestNegotiateOffers(t *testing.T) {
+	render := New()
+
+	var err error
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err = render.Negotiate(w, r, http.StatusOK, GreetingXML{One: "hello", Two: "world"}, NegotiateOptions{
+			Offers: []string{ContentXML, ContentJSON},
+		})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	req.Header.Set("Accept", "*/*")
+	h.ServeHTTP(res, req)
+
+	expectNil(t, err)
+	expect(t, res.Header().Get(ContentType), ContentXML+"; charset=UTF-8")
+}
+
+func TestNegotiateCustomEngine(t *testing.T) {
+	render := New()
+	render.RegisterEngine("text/csv", func(head Head) Engine {
+		return CSVGreeting{Head: head}
+	})
+
+	var err error
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err = render.Negotiate(w, r, http.StatusOK, Greeting{"hello", "world"})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	req.Header.Set("Accept", "text/csv, application/json;q=0.9")
+	h.ServeHTTP(res, req)
+
+	expectNil(t, err)
+	expect(t, res.Header().Get(ContentType), "text/csv")
+	expect(t, res.Body.String(), "one,two\nhello,world\n")
+}
+
+func TestNegotiateVaryNotDuplicated(t *testing.T) {
+	render := New()
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Vary", "Origin, accept")
+		render.Negotiate(w, r, http.StatusOK, Greeting{"hello", "world"})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	h.ServeHTTP(res, req)
+
+	expect(t, len(res.Header()["Vary"]), 1)
+	expect(t, res.Header().Get("Vary"), "Origin, accept")
+}
@@ -0,0 +1,120 @@
+package render
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+type partialUser struct {
+	Name  string
+	Admin bool
+}
+
+func partialDataFS() fstest.MapFS {
+	return fstest.MapFS{
+		"layout.tmpl": {Data: []byte(`<main>{{ yield }}</main>{{ partialWith "card" (dict "Name" "layout" "Admin" false) }}`)},
+		"card.tmpl":   {Data: []byte(`<card>{{ .Name }}{{ if .Admin }} (admin){{ end }}</card>`)},
+		"users.tmpl":  {Data: []byte(`{{ range . }}{{ partialWith "card" . }}{{ end }}`)},
+		"team.tmpl":   {Data: []byte(`{{ define "card-team" }}<member>{{ .Name }}</member>{{ end }}{{ partialWith "card" (index . 0) }}`)},
+		"empty.tmpl":  {Data: []byte(`{{ partialWith "missing" . }}empty`)},
+		"broken.tmpl": {Data: []byte(`{{ partialWith "card" (dict "Name") }}`)},
+	}
+}
+
+func TestHTMLPartialWith(t *testing.T) {
+	render := New(Options{
+		FS:                          partialDataFS(),
+		Layout:                      "layout",
+		RenderPartialsWithoutPrefix: true,
+	})
+
+	var err error
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err = render.HTML(w, http.StatusOK, "users", []partialUser{{"alice", true}, {"bob", false}})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	h.ServeHTTP(res, req)
+
+	expectNil(t, err)
+	expect(t, res.Body.String(), "<main><card>alice (admin)</card><card>bob</card></main><card>layout</card>")
+}
+
+func TestHTMLPartialWithPageSuffix(t *testing.T) {
+	render := New(Options{
+		FS:                          partialDataFS(),
+		RenderPartialsWithoutPrefix: true,
+	})
+
+	res := httptest.NewRecorder()
+	err := render.HTML(res, http.StatusOK, "team", []partialUser{{"carol", false}})
+
+	expectNil(t, err)
+	expect(t, res.Body.String(), "<member>carol</member>")
+}
+
+func TestHTMLPartialWithoutPrefixDisabled(t *testing.T) {
+	render := New(Options{
+		FS: partialDataFS(),
+	})
+
+	res := httptest.NewRecorder()
+	err := render.HTML(res, http.StatusOK, "users", []partialUser{{"alice", true}})
+
+	expectNil(t, err)
+	expect(t, res.Body.String(), "")
+}
+
+func TestHTMLPartialWithRequirePartials(t *testing.T) {
+	render := New(Options{
+		FS:              partialDataFS(),
+		RequirePartials: true,
+	})
+
+	res := httptest.NewRecorder()
+	err := render.HTML(res, http.StatusOK, "empty", nil)
+
+	expectNotNil(t, err)
+	expect(t, res.Code, http.StatusInternalServerError)
+
+	render = New(Options{
+		FS: partialDataFS(),
+	})
+
+	res = httptest.NewRecorder()
+	err = render.HTML(res, http.StatusOK, "empty", nil)
+
+	expectNil(t, err)
+	expect(t, res.Body.String(), "empty")
+}
+
+func TestHTMLPartialWithBadDict(t *testing.T) {
+	render := New(Options{
+		FS:                          partialDataFS(),
+		RenderPartialsWithoutPrefix: true,
+	})
+
+	res := httptest.NewRecorder()
+	err := render.HTML(res, http.StatusOK, "broken", nil)
+
+	expectNotNil(t, err)
+	expect(t, res.Code, http.StatusInternalServerError)
+}
+
+func TestDict(t *testing.T) {
+	m, err := dict("one", 1, "two", "2")
+
+	expectNil(t, err)
+	expect(t, len(m), 2)
+	expect(t, m["one"], 1)
+	expect(t, m["two"], "2")
+
+	_, err = dict(1, "one")
+	expectNotNil(t, err)
+
+	_, err = dict("one")
+	expectNotNil(t, err)
+}
@@ -0,0 +1,139 @@
+package render
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestProblemJSON(t *testing.T) {
+	render := New()
+
+	var err error
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err = render.Problem(w, Problem{
+			Type:     "https://example.com/probs/out-of-credit",
+			Title:    "You do not have enough credit.",
+			Status:   http.StatusForbidden,
+			Detail:   "Your current balance is 30, but that costs 50.",
+			Instance: "/account/12345/msgs/abc",
+			Extensions: map[string]interface{}{
+				"balance": 30,
+				"status":  200,
+			},
+		})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	h.ServeHTTP(res, req)
+
+	expectNil(t, err)
+	expect(t, res.Code, http.StatusForbidden)
+	expect(t, res.Header().Get(ContentType), ContentProblemJSON+"; charset=UTF-8")
+	expect(t, res.Body.String(), `{"type":"https://example.com/probs/out-of-credit","title":"You do not have enough credit.","status":403,"detail":"Your current balance is 30, but that costs 50.","instance":"/account/12345/msgs/abc","balance":30}`)
+}
+
+func TestProblemJSONDefaults(t *testing.T) {
+	render := New()
+
+	res := httptest.NewRecorder()
+	err := render.Problem(res, Problem{
+		Extensions: map[string]interface{}{
+			"traceId": "abc",
+		},
+	})
+
+	expectNil(t, err)
+	expect(t, res.Code, http.StatusInternalServerError)
+	expect(t, res.Body.String(), `{"title":"Internal Server Error","status":500,"traceId":"abc"}`)
+}
+
+func TestProblemXML(t *testing.T) {
+	render := New()
+
+	res := httptest.NewRecorder()
+	err := render.ProblemXML(res, Problem{
+		Status: http.StatusNotFound,
+		Detail: "No such user.",
+		Extensions: map[string]interface{}{
+			"user": "gopher",
+		},
+	})
+
+	expectNil(t, err)
+	expect(t, res.Code, http.StatusNotFound)
+	expect(t, res.Header().Get(ContentType), ContentProblemXML+"; charset=UTF-8")
+	expect(t, res.Body.String(), `<problem xmlns="urn:ietf:rfc:7807"><title>Not Found</title><status>404</status><detail>No such user.</detail><user>gopher</user></problem>`)
+}
+
+func TestProblemHeaders(t *testing.T) {
+	render := New()
+
+	res := httptest.NewRecorder()
+	err := render.Problem(res, Problem{
+		Status:  http.StatusTooManyRequests,
+		Headers: http.Header{"Retry-After": {"120"}},
+		Cookies: []*http.Cookie{{Name: "throttled", Value: "1"}},
+	})
+
+	expectNil(t, err)
+	expect(t, res.Code, http.StatusTooManyRequests)
+	expect(t, res.Header().Get("Retry-After"), "120")
+	expect(t, res.Header().Get("Set-Cookie"), "throttled=1")
+	expect(t, res.Body.String(), `{"title":"Too Many Requests","status":429}`)
+}
+
+func TestProblemDetailsOnRenderError(t *testing.T) {
+	render := New(Options{
+		Directory:      "fixtures/basic",
+		ProblemDetails: true,
+	})
+
+	res := httptest.NewRecorder()
+	err := render.HTML(res, http.StatusOK, "nope", nil)
+
+	expectNotNil(t, err)
+	expect(t, res.Code, http.StatusInternalServerError)
+	expect(t, res.Header().Get(ContentType), ContentProblemJSON+"; charset=UTF-8")
+	expect(t, res.Header().Get("X-Content-Type-Options"), "nosniff")
+	expect(t, res.Body.String(), `{"title":"Internal Server Error","status":500}`)
+}
+
+func TestProblemDetailsOnRenderErrorDevelopment(t *testing.T) {
+	render := New(Options{
+		ProblemDetails: true,
+		IsDevelopment:  true,
+	})
+
+	res := httptest.NewRecorder()
+	err := render.JSON(res, http.StatusOK, errorMarshaler{})
+
+	expectNotNil(t, err)
+	expect(t, res.Code, http.StatusInternalServerError)
+	expect(t, strings.HasPrefix(res.Body.String(), `{"title":"Internal Server Error","status":500,"detail":"json: error calling MarshalJSON`), true)
+	expect(t, strings.HasSuffix(res.Body.String(), `boom"}`), true)
+}
+
+func TestProblemDetailsNotAcceptable(t *testing.T) {
+	render := New(Options{
+		ProblemDetails: true,
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	req.Header.Set("Accept", "image/png")
+	err := render.Negotiate(res, req, http.StatusOK, Greeting{"hello", "world"})
+
+	expect(t, err, ErrNotAcceptable)
+	expect(t, res.Code, http.StatusNotAcceptable)
+	expect(t, res.Body.String(), `{"title":"Not Acceptable","status":406}`)
+}
+
+type errorMarshaler struct{}
+
+func (errorMarshaler) MarshalJSON() ([]byte, error) {
+	return nil, errors.New("boom")
+}
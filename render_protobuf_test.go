@@ -0,0 +1,208 @@
+package render
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// protoGreeting marshals itself like a generated protobuf message with the
+// string fields one = 1 and two = 2.
+type protoGreeting struct {
+	One string `json:"one,omitempty"`
+	Two string `json:"two,omitempty"`
+}
+
+func (g *protoGreeting) Marshal() ([]byte, error) {
+	if g.One == "fail" {
+		return nil, errors.New("proto: cannot marshal")
+	}
+
+	b := []byte{0x0a, byte(len(g.One))}
+	b = append(b, g.One...)
+	b = append(b, 0x12, byte(len(g.Two)))
+	return append(b, g.Two...), nil
+}
+
+// binaryGreeting marshals itself with encoding.BinaryMarshaler.
+type binaryGreeting struct {
+	protoGreeting
+}
+
+func (g binaryGreeting) MarshalBinary() ([]byte, error) {
+	return g.protoGreeting.Marshal()
+}
+
+// reflectGreeting only has a ProtoReflect method, like the messages generated
+// by google.golang.org/protobuf.
+type reflectGreeting struct {
+	msg protoGreeting
+}
+
+func (g *reflectGreeting) ProtoReflect() interface{} {
+	return g
+}
+
+func protoMarshal(v interface{}) ([]byte, error) {
+	return v.(*reflectGreeting).msg.Marshal()
+}
+
+func protoJSON(v interface{}) ([]byte, error) {
+	g := v.(*protoGreeting)
+	return []byte(`{"one":"` + g.One + `","two":"` + g.Two + `"}`), nil
+}
+
+func TestProtobufBasic(t *testing.T) {
+	render := New()
+
+	var err error
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err = render.Protobuf(w, 299, &protoGreeting{"hello", "world"})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	h.ServeHTTP(res, req)
+
+	expectNil(t, err)
+	expect(t, res.Code, 299)
+	expect(t, res.Header().Get(ContentType), ContentProtobuf)
+	expect(t, res.Body.String(), "\x0a\x05hello\x12\x05world")
+}
+
+func TestProtobufCustomContentType(t *testing.T) {
+	render := New(Options{
+		ProtobufContentType: "application/protobuf",
+	})
+
+	res := httptest.NewRecorder()
+	err := render.Protobuf(res, http.StatusOK, &protoGreeting{"hello", "world"})
+
+	expectNil(t, err)
+	expect(t, res.Header().Get(ContentType), "application/protobuf")
+}
+
+func TestProtobufMarshalError(t *testing.T) {
+	render := New()
+
+	res := httptest.NewRecorder()
+	err := render.Protobuf(res, http.StatusOK, &protoGreeting{"fail", "world"})
+
+	expectNotNil(t, err)
+	expect(t, res.Code, http.StatusInternalServerError)
+}
+
+func TestProtobufNotAMessage(t *testing.T) {
+	render := New()
+
+	res := httptest.NewRecorder()
+	err := render.Protobuf(res, http.StatusOK, Greeting{"hello", "world"})
+
+	expectNotNil(t, err)
+	expect(t, res.Code, http.StatusInternalServerError)
+}
+
+func TestProtobufBinaryMarshaler(t *testing.T) {
+	render := New()
+
+	res := httptest.NewRecorder()
+	err := render.Protobuf(res, http.StatusOK, binaryGreeting{protoGreeting{"hello", "world"}})
+
+	expectNil(t, err)
+	expect(t, res.Header().Get(ContentType), ContentProtobuf)
+	expect(t, res.Body.String(), "\x0a\x05hello\x12\x05world")
+}
+
+func TestProtobufMarshalOption(t *testing.T) {
+	render := New(Options{
+		ProtobufMarshal: protoMarshal,
+	})
+
+	res := httptest.NewRecorder()
+	err := render.Protobuf(res, http.StatusOK, &reflectGreeting{protoGreeting{"hello", "world"}})
+
+	expectNil(t, err)
+	expect(t, res.Header().Get(ContentType), ContentProtobuf)
+	expect(t, res.Body.String(), "\x0a\x05hello\x12\x05world")
+}
+
+func TestProtobufJSON(t *testing.T) {
+	render := New(Options{
+		ProtobufJSON: protoJSON,
+	})
+
+	res := httptest.NewRecorder()
+	err := render.ProtobufJSON(res, http.StatusOK, &protoGreeting{Two: "world"})
+
+	expectNil(t, err)
+	expect(t, res.Header().Get(ContentType), ContentJSON+"; charset=UTF-8")
+	expect(t, res.Body.String(), `{"one":"","two":"world"}`)
+}
+
+func TestProtobufJSONDefault(t *testing.T) {
+	render := New()
+
+	res := httptest.NewRecorder()
+	err := render.ProtobufJSON(res, http.StatusOK, &protoGreeting{Two: "world"})
+
+	expectNil(t, err)
+	expect(t, res.Header().Get(ContentType), ContentJSON+"; charset=UTF-8")
+	expect(t, res.Body.String(), `{"two":"world"}`)
+}
+
+func TestNegotiateProtobuf(t *testing.T) {
+	render := New(Options{
+		ProtobufJSON: protoJSON,
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	req.Header.Set("Accept", "application/x-protobuf, application/json;q=0.5")
+	err := render.Negotiate(res, req, http.StatusOK, &protoGreeting{"hello", "world"})
+
+	expectNil(t, err)
+	expect(t, res.Header().Get(ContentType), ContentProtobuf)
+	expect(t, res.Body.String(), "\x0a\x05hello\x12\x05world")
+
+	res = httptest.NewRecorder()
+	req.Header.Set("Accept", "application/json")
+	err = render.Negotiate(res, req, http.StatusOK, &protoGreeting{One: "hello"})
+
+	expectNil(t, err)
+	expect(t, res.Header().Get(ContentType), ContentJSON+"; charset=UTF-8")
+	expect(t, res.Body.String(), `{"one":"hello","two":""}`)
+}
+
+func TestNegotiateProtobufOnlyForMessages(t *testing.T) {
+	render := New()
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	req.Header.Set("Accept", "application/x-protobuf")
+	err := render.Negotiate(res, req, http.StatusOK, Greeting{"hello", "world"})
+
+	expect(t, err, ErrNotAcceptable)
+	expect(t, res.Code, http.StatusNotAcceptable)
+}
+
+func TestNegotiateProtobufMarshalOption(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	req.Header.Set("Accept", "application/x-protobuf")
+
+	res := httptest.NewRecorder()
+	err := New().Negotiate(res, req, http.StatusOK, &reflectGreeting{protoGreeting{"hello", "world"}})
+
+	expect(t, err, ErrNotAcceptable)
+
+	render := New(Options{
+		ProtobufMarshal: protoMarshal,
+	})
+
+	res = httptest.NewRecorder()
+	err = render.Negotiate(res, req, http.StatusOK, &reflectGreeting{protoGreeting{"hello", "world"}})
+
+	expectNil(t, err)
+	expect(t, res.Header().Get(ContentType), ContentProtobuf)
+	expect(t, res.Body.String(), "\x0a\x05hello\x12\x05world")
+}
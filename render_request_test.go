@@ -0,0 +1,152 @@
+package render
+
+import (
+	"context"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newRequestRender() *Render {
+	return New(Options{
+		Directory: "fixtures/request",
+		Funcs: []template.FuncMap{
+			{
+				"csrf": func() string { return "" },
+			},
+		},
+		RequestFuncs: func(req *http.Request) template.FuncMap {
+			return template.FuncMap{
+				"csrf": func() string { return req.Header.Get("X-CSRF-Token") },
+			}
+		},
+		RequestData: func(req *http.Request) map[string]interface{} {
+			return map[string]interface{}{
+				"user":   "anonymous",
+				"locale": req.Header.Get("Accept-Language"),
+			}
+		},
+	})
+}
+
+func TestHTMLRequest(t *testing.T) {
+	render := newRequestRender()
+
+	var err error
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err = render.HTMLRequest(w, r, http.StatusOK, "hello", nil)
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	req.Header.Set("X-CSRF-Token", "token")
+	h.ServeHTTP(res, req)
+
+	expectNil(t, err)
+	expect(t, res.Code, http.StatusOK)
+	expect(t, res.Header().Get(ContentType), ContentHTML+"; charset=UTF-8")
+	expect(t, res.Body.String(), "<p>anonymous token /foo</p>\n")
+}
+
+func TestHTMLRequestBindingOverridesData(t *testing.T) {
+	render := newRequestRender()
+
+	var err error
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err = render.HTMLRequest(w, r, http.StatusOK, "hello", map[string]interface{}{"user": "gopher"}, HTMLOptions{
+			Layout: "layout",
+			Funcs: template.FuncMap{
+				"csrf": func() string { return "call" },
+			},
+		})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	req.Header.Set("X-CSRF-Token", "token")
+	req.Header.Set("Accept-Language", "en")
+	h.ServeHTTP(res, req)
+
+	expectNil(t, err)
+	expect(t, res.Body.String(), "<title>en</title>\n<p>gopher call /foo</p>\n")
+}
+
+func TestHTMLRequestStructBinding(t *testing.T) {
+	render := newRequestRender()
+
+	var err error
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err = render.HTMLRequest(w, r, http.StatusOK, "struct", Greeting{"hello", "world"})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	h.ServeHTTP(res, req)
+
+	expectNil(t, err)
+	expect(t, res.Body.String(), "<p>hello anonymous</p>\n")
+}
+
+func TestHTMLWithoutRequest(t *testing.T) {
+	render := newRequestRender()
+
+	res := httptest.NewRecorder()
+	err := render.HTML(res, http.StatusOK, "struct", Greeting{"hello", "world"})
+
+	expectNil(t, err)
+	expect(t, res.Body.String(), "<p>hello </p>\n")
+}
+
+func TestRequestCanceled(t *testing.T) {
+	render := newRequestRender()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	req = req.WithContext(ctx)
+
+	calls := map[string]func(w http.ResponseWriter) error{
+		"html": func(w http.ResponseWriter) error {
+			return render.HTMLRequest(w, req, http.StatusOK, "hello", nil)
+		},
+		"json": func(w http.ResponseWriter) error {
+			return render.JSONRequest(w, req, http.StatusOK, Greeting{"hello", "world"})
+		},
+		"xml": func(w http.ResponseWriter) error {
+			return render.XMLRequest(w, req, http.StatusOK, GreetingXML{One: "hello", Two: "world"})
+		},
+		"text": func(w http.ResponseWriter) error {
+			return render.TextRequest(w, req, http.StatusOK, "hello")
+		},
+	}
+
+	for name, call := range calls {
+		res := httptest.NewRecorder()
+		err := call(res)
+
+		if err != context.Canceled {
+			t.Errorf("%s: expected context.Canceled, got %v", name, err)
+		}
+		if res.Body.Len() != 0 {
+			t.Errorf("%s: expected no body, got %q", name, res.Body.String())
+		}
+	}
+}
+
+func TestJSONRequest(t *testing.T) {
+	render := New()
+
+	var err error
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err = render.JSONRequest(w, r, 299, Greeting{"hello", "world"})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	h.ServeHTTP(res, req)
+
+	expectNil(t, err)
+	expect(t, res.Code, 299)
+	expect(t, res.Body.String(), "{\"one\":\"hello\",\"two\":\"world\"}")
+}
@@ -0,0 +1,101 @@
+package render
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestHTMLSections(t *testing.T) {
+	render := New(Options{
+		Directory: "fixtures/sections",
+		Layout:    "layout",
+	})
+
+	var err error
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err = render.HTML(w, http.StatusOK, "home", "gophers")
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	h.ServeHTTP(res, req)
+
+	expectNil(t, err)
+	expect(t, res.Body.String(), "<title>Home of gophers</title>\n<h1>gophers</h1>\n<script src=\"home.js\"></script>\n")
+}
+
+func TestHTMLSectionsFallback(t *testing.T) {
+	render := New(Options{
+		Directory: "fixtures/sections",
+		Layout:    "layout",
+	})
+
+	res := httptest.NewRecorder()
+	err := render.HTML(res, http.StatusOK, "about", nil)
+
+	expectNil(t, err)
+	expect(t, res.Body.String(), "<title>Untitled &amp; Co</title>\n<h1>About</h1>\n\n")
+}
+
+func TestHTMLSectionsAsPartials(t *testing.T) {
+	render := New(Options{
+		FS: fstest.MapFS{
+			"layout.tmpl": {Data: []byte(`<title>{{ partial "title" }}</title>{{ yield }}`)},
+			"home.tmpl":   {Data: []byte(`{{ define "section:title" }}Home{{ end }}home`)},
+		},
+		Layout: "layout",
+	})
+
+	res := httptest.NewRecorder()
+	err := render.HTML(res, http.StatusOK, "home", nil)
+
+	expectNil(t, err)
+	expect(t, res.Body.String(), "<title>Home</title>home")
+}
+
+func TestHTMLSectionsInLayoutChain(t *testing.T) {
+	render := New(Options{
+		FS: fstest.MapFS{
+			"base.tmpl":  {Data: []byte(`<h1>{{ section "title" (safe "<b>App</b>") }}</h1><nav>{{ section "nav" }}</nav>{{ yield }}`)},
+			"admin.tmpl": {Data: []byte(`{{ layout "base" }}{{ define "section:title" }}Admin{{ end }}{{ define "section:nav" }}admin nav{{ end }}<admin>{{ yield }}</admin>`)},
+			"users.tmpl": {Data: []byte(`{{ define "section:title" }}Users{{ end }}users`)},
+			"stats.tmpl": {Data: []byte(`stats`)},
+		},
+		Funcs: []template.FuncMap{{
+			"safe": func(s string) template.HTML { return template.HTML(s) },
+		}},
+	})
+
+	res := httptest.NewRecorder()
+	err := render.HTML(res, http.StatusOK, "users", nil, HTMLOptions{Layout: "admin"})
+
+	expectNil(t, err)
+	expect(t, res.Body.String(), "<h1>Users</h1><nav>admin nav</nav><admin>users</admin>")
+
+	res = httptest.NewRecorder()
+	err = render.HTML(res, http.StatusOK, "stats", nil, HTMLOptions{Layout: "admin"})
+
+	expectNil(t, err)
+	expect(t, res.Body.String(), "<h1>Admin</h1><nav>admin nav</nav><admin>stats</admin>")
+
+	res = httptest.NewRecorder()
+	err = render.HTML(res, http.StatusOK, "stats", nil, HTMLOptions{Layout: "base"})
+
+	expectNil(t, err)
+	expect(t, res.Body.String(), "<h1><b>App</b></h1><nav></nav>stats")
+}
+
+func TestHTMLSectionWithoutLayout(t *testing.T) {
+	render := New(Options{
+		Directory: "fixtures/sections",
+	})
+
+	res := httptest.NewRecorder()
+	err := render.HTML(res, http.StatusOK, "layout", nil)
+
+	expectNotNil(t, err)
+	expect(t, res.Code, http.StatusInternalServerError)
+}
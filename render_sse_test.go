@@ -0,0 +1,194 @@
+package render
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSSEEvents(t *testing.T) {
+	render := New()
+
+	events := []Event{
+		{ID: "1", Event: "greeting", Data: Greeting{"hello", "world"}},
+		{ID: "2", Data: "two\nlines", Retry: 3 * time.Second},
+		{Event: "ping"},
+	}
+
+	var err error
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err = render.SSE(w, r, events)
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	h.ServeHTTP(res, req)
+
+	expectNil(t, err)
+	expect(t, res.Code, http.StatusOK)
+	expect(t, res.Header().Get(ContentType), ContentEventStream+"; charset=UTF-8")
+	expect(t, res.Header().Get("Cache-Control"), "no-cache")
+	expect(t, res.Flushed, true)
+	expect(t, res.Body.String(), "id: 1\nevent: greeting\ndata: {\"one\":\"hello\",\"two\":\"world\"}\n\n"+
+		"id: 2\nretry: 3000\ndata: two\ndata: lines\n\n"+
+		"event: ping\n\n")
+}
+
+func TestSSEHeaders(t *testing.T) {
+	render := New()
+
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	res := httptest.NewRecorder()
+	err := render.SSE(res, req, []Event{{Data: "hello"}}, SSEOptions{
+		Headers: http.Header{"Cache-Control": {"no-store"}},
+		Cookies: []*http.Cookie{{Name: "stream", Value: "open"}},
+	})
+
+	expectNil(t, err)
+	expect(t, res.Header().Get("Cache-Control"), "no-store")
+	expect(t, res.Header().Get("X-Accel-Buffering"), "no")
+	expect(t, res.Header().Get("Set-Cookie"), "stream=open")
+	expect(t, res.Body.String(), "data: hello\n\n")
+}
+
+func TestSSEPlainValuesAndJSONOptions(t *testing.T) {
+	render := New(Options{
+		IndentJSON:   true,
+		UnEscapeHTML: true,
+	})
+
+	ch := make(chan interface{}, 2)
+	ch <- Greeting{"<b>", "&"}
+	ch <- &Event{ID: "x\ny", Data: []byte("raw")}
+	close(ch)
+
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	res := httptest.NewRecorder()
+	err := render.SSE(res, req, ch)
+
+	expectNil(t, err)
+	expect(t, res.Body.String(), "data: {\ndata:   \"one\": \"<b>\",\ndata:   \"two\": \"&\"\ndata: }\n\n"+
+		"id: xy\ndata: raw\n\n")
+}
+
+func TestSSEPartial(t *testing.T) {
+	render := New(Options{
+		Directory: "fixtures/sse",
+	})
+
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	res := httptest.NewRecorder()
+	err := render.SSE(res, req, []Event{{Event: "item", Data: Greeting{"<hello>", "world"}}}, SSEOptions{
+		Partial: "item",
+	})
+
+	expectNil(t, err)
+	expect(t, res.Body.String(), "event: item\ndata: <li>&lt;hello&gt;</li>\ndata: <li>world</li>\n\n")
+}
+
+func TestSSEResumeSlice(t *testing.T) {
+	render := New()
+
+	events := []Event{{ID: "1", Data: "a"}, {ID: "2", Data: "b"}, {ID: "3", Data: "c"}}
+
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	req.Header.Set("Last-Event-ID", "2")
+	res := httptest.NewRecorder()
+	err := render.SSE(res, req, events)
+
+	expectNil(t, err)
+	expect(t, res.Body.String(), "id: 3\ndata: c\n\n")
+
+	req.Header.Set("Last-Event-ID", "unknown")
+	res = httptest.NewRecorder()
+	err = render.SSE(res, req, events)
+
+	expectNil(t, err)
+	expect(t, strings.Count(res.Body.String(), "data:"), 3)
+}
+
+func TestSSEResumeIterator(t *testing.T) {
+	render := New()
+
+	var resumedFrom string
+	it := ResumeIterator(func(lastEventID string, yield func(v interface{}) error) error {
+		resumedFrom = lastEventID
+		return yield(Event{ID: "8", Data: "next"})
+	})
+
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	req.Header.Set("Last-Event-ID", "7")
+	res := httptest.NewRecorder()
+	err := render.SSE(res, req, it)
+
+	expectNil(t, err)
+	expect(t, resumedFrom, "7")
+	expect(t, res.Body.String(), "id: 8\ndata: next\n\n")
+}
+
+func TestSSEKeepAlive(t *testing.T) {
+	render := New()
+
+	ch := make(chan Event)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		ch <- Event{Data: "late"}
+		close(ch)
+	}()
+
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	res := httptest.NewRecorder()
+	err := render.SSE(res, req, ch, SSEOptions{
+		KeepAlive: 20 * time.Millisecond,
+	})
+
+	expectNil(t, err)
+	expect(t, strings.HasPrefix(res.Body.String(), ": keep-alive\n\n"), true)
+	expect(t, strings.HasSuffix(res.Body.String(), "data: late\n\n"), true)
+}
+
+func TestSSEKeepAliveDisabled(t *testing.T) {
+	render := New(Options{
+		SSEKeepAlive: -1,
+	})
+
+	ch := make(chan Event)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(ch)
+	}()
+
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	res := httptest.NewRecorder()
+	err := render.SSE(res, req, ch)
+
+	expectNil(t, err)
+	expect(t, res.Body.String(), "")
+}
+
+func TestSSECanceled(t *testing.T) {
+	render := New()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	req = req.WithContext(ctx)
+
+	ch := make(chan Event)
+	go func() {
+		ch <- Event{Data: "first"}
+		cancel()
+	}()
+
+	res := httptest.NewRecorder()
+	err := render.SSE(res, req, ch)
+
+	var streamErr *StreamError
+	expect(t, errors.As(err, &streamErr), true)
+	expect(t, errors.Is(err, context.Canceled), true)
+	expect(t, streamErr.Written, 1)
+	expect(t, res.Body.String(), "data: first\n\n")
+}
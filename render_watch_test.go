@@ -0,0 +1,178 @@
+package render
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTemplate writes a template file and moves its modification time
+// forward, so the change is seen regardless of the file system's resolution.
+func writeTemplate(t *testing.T, file, content string, age time.Duration) {
+	expectNil(t, ioutil.WriteFile(file, []byte(content), 0644))
+	mtime := time.Now().Add(age)
+	expectNil(t, os.Chtimes(file, mtime, mtime))
+}
+
+func renderString(t *testing.T, render *Render, name string) string {
+	res := httptest.NewRecorder()
+	err := render.HTML(res, http.StatusOK, name, "gophers")
+	expectNil(t, err)
+	return res.Body.String()
+}
+
+func TestReloadChangedTemplates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "render")
+	expectNil(t, err)
+	defer os.RemoveAll(dir)
+
+	hello := filepath.Join(dir, "hello.tmpl")
+	other := filepath.Join(dir, "other.tmpl")
+	writeTemplate(t, hello, "<h1>Hello {{ . }}</h1>", -time.Hour)
+	writeTemplate(t, other, "<p>Other {{ . }}</p>", -time.Hour)
+
+	var events []ReloadEvent
+	render := New(Options{
+		Directory: dir,
+		OnReload: func(e ReloadEvent) {
+			events = append(events, e)
+		},
+	})
+
+	expectNil(t, render.Reload())
+	expect(t, len(events), 0)
+
+	writeTemplate(t, hello, "<h2>Hello {{ . }}</h2>", 0)
+	added := filepath.Join(dir, "added.tmpl")
+	writeTemplate(t, added, "<p>Added {{ . }}</p>", 0)
+
+	expectNil(t, render.Reload())
+	expect(t, len(events), 1)
+	expect(t, len(events[0].Added), 1)
+	expect(t, events[0].Added[0], added)
+	expect(t, len(events[0].Changed), 1)
+	expect(t, events[0].Changed[0], hello)
+	expect(t, len(events[0].Removed), 0)
+
+	expect(t, renderString(t, render, "hello"), "<h2>Hello gophers</h2>")
+	expect(t, renderString(t, render, "other"), "<p>Other gophers</p>")
+	expect(t, renderString(t, render, "added"), "<p>Added gophers</p>")
+
+	expectNil(t, os.Remove(added))
+	expectNil(t, render.Reload())
+	expect(t, len(events), 2)
+	expect(t, len(events[1].Removed), 1)
+	expect(t, events[1].Removed[0], added)
+	expect(t, render.TemplateLookup("added") == nil, true)
+}
+
+func TestReloadKeepsTemplatesOnError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "render")
+	expectNil(t, err)
+	defer os.RemoveAll(dir)
+
+	hello := filepath.Join(dir, "hello.tmpl")
+	writeTemplate(t, hello, "<h1>Hello {{ . }}</h1>", -time.Hour)
+
+	var events []ReloadEvent
+	render := New(Options{
+		Directory: dir,
+		OnReload: func(e ReloadEvent) {
+			events = append(events, e)
+		},
+	})
+
+	writeTemplate(t, hello, "<h1>Hello {{ . </h1>", 0)
+
+	_, ok := render.Reload().(*CompileError)
+	expect(t, ok, true)
+	expect(t, len(events), 1)
+	_, ok = events[0].Err.(*CompileError)
+	expect(t, ok, true)
+	expect(t, renderString(t, render, "hello"), "<h1>Hello gophers</h1>")
+
+	// The same failure is not reported again.
+	expectNil(t, render.Reload())
+	expect(t, len(events), 1)
+
+	writeTemplate(t, hello, "<h3>Hello {{ . }}</h3>", time.Hour)
+	expectNil(t, render.Reload())
+	expect(t, len(events), 2)
+	expectNil(t, events[1].Err)
+	expect(t, renderString(t, render, "hello"), "<h3>Hello gophers</h3>")
+}
+
+func TestReloadDropsRemovedDefines(t *testing.T) {
+	dir, err := ioutil.TempDir("", "render")
+	expectNil(t, err)
+	defer os.RemoveAll(dir)
+
+	layout := filepath.Join(dir, "layout.tmpl")
+	home := filepath.Join(dir, "home.tmpl")
+	writeTemplate(t, layout, `<title>{{ section "title" "Default" }}</title>{{ yield }}`, -time.Hour)
+	writeTemplate(t, home, `{{ define "section:title" }}Home{{ end }}<h1>{{ . }}</h1>`, -time.Hour)
+
+	render := New(Options{
+		Directory: dir,
+		Layout:    "layout",
+	})
+	expect(t, renderString(t, render, "home"), "<title>Home</title><h1>gophers</h1>")
+
+	writeTemplate(t, home, `<h1>{{ . }}</h1>`, 0)
+	expectNil(t, render.Reload())
+	expect(t, renderString(t, render, "home"), "<title>Default</title><h1>gophers</h1>")
+	expect(t, render.TemplateLookup("section:title") == nil, true)
+}
+
+func TestWatchTemplates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "render")
+	expectNil(t, err)
+	defer os.RemoveAll(dir)
+
+	hello := filepath.Join(dir, "hello.tmpl")
+	writeTemplate(t, hello, "<h1>Hello {{ . }}</h1>", -time.Hour)
+
+	reloaded := make(chan ReloadEvent, 1)
+	render := New(Options{
+		Directory:      dir,
+		IsDevelopment:  true,
+		WatchTemplates: true,
+		WatchInterval:  10 * time.Millisecond,
+		OnReload: func(e ReloadEvent) {
+			reloaded <- e
+		},
+	})
+	defer render.Close()
+
+	writeTemplate(t, hello, "<h2>Hello {{ . }}</h2>", 0)
+
+	select {
+	case e := <-reloaded:
+		expectNil(t, e.Err)
+		expect(t, len(e.Changed), 1)
+	case <-time.After(5 * time.Second):
+		t.Fatal("templates were not reloaded")
+	}
+
+	expect(t, renderString(t, render, "hello"), "<h2>Hello gophers</h2>")
+}
+
+func TestReloadAssets(t *testing.T) {
+	content := "<h1>{{ . }}</h1>"
+	render := New(Options{
+		Asset: func(file string) ([]byte, error) {
+			return []byte(content), nil
+		},
+		AssetNames: func() []string {
+			return []string{"templates/test.tmpl"}
+		},
+	})
+
+	content = "<h2>{{ . }}</h2>"
+	expectNil(t, render.Reload())
+	expect(t, renderString(t, render, "test"), "<h2>gophers</h2>")
+}
@@ -0,0 +1,205 @@
+package render
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type yamlServer struct {
+	Name     string            `json:"name"`
+	Port     int               `json:"port"`
+	TLS      bool              `json:"tls"`
+	Hosts    []string          `json:"hosts"`
+	Labels   map[string]string `json:"labels"`
+	Limits   *yamlLimits       `json:"limits,omitempty"`
+	Backends []yamlLimits      `json:"backends"`
+	Skipped  string            `json:"-"`
+}
+
+type yamlLimits struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+func TestYAMLBasic(t *testing.T) {
+	render := New()
+
+	var err error
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err = render.YAML(w, 299, Greeting{"hello", "world"})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	h.ServeHTTP(res, req)
+
+	expectNil(t, err)
+	expect(t, res.Code, 299)
+	expect(t, res.Header().Get(ContentType), ContentYAML+"; charset=UTF-8")
+	expect(t, res.Body.String(), "one: hello\ntwo: world\n")
+}
+
+func TestYAMLNested(t *testing.T) {
+	render := New()
+
+	v := yamlServer{
+		Name:   "api",
+		Port:   8080,
+		TLS:    true,
+		Hosts:  []string{"a.example.com", "b.example.com"},
+		Labels: map[string]string{"zone": "eu", "env": "prod"},
+		Limits: &yamlLimits{Rate: 1.5, Burst: 10},
+		Backends: []yamlLimits{
+			{Rate: 1, Burst: 2},
+		},
+		Skipped: "secret",
+	}
+
+	res := httptest.NewRecorder()
+	err := render.YAML(res, http.StatusOK, v)
+
+	expectNil(t, err)
+	expect(t, res.Body.String(), `name: api
+port: 8080
+tls: true
+hosts:
+  - a.example.com
+  - b.example.com
+labels:
+  env: prod
+  zone: eu
+limits:
+  rate: 1.5
+  burst: 10
+backends:
+  - rate: 1
+    burst: 2
+`)
+}
+
+func TestYAMLIndent(t *testing.T) {
+	render := New(Options{
+		IndentYAML: 4,
+	})
+
+	v := map[string]interface{}{
+		"list":   []interface{}{[]int{1, 2}, map[string]int{"a": 1, "b": 2}},
+		"nested": map[string]interface{}{"empty": map[string]int{}, "none": []int{}},
+	}
+
+	res := httptest.NewRecorder()
+	err := render.YAML(res, http.StatusOK, v)
+
+	expectNil(t, err)
+	expect(t, res.Body.String(), `list:
+    -   - 1
+        - 2
+    -   a: 1
+        b: 2
+nested:
+    empty: {}
+    none: []
+`)
+}
+
+func TestYAMLQuoting(t *testing.T) {
+	render := New()
+
+	v := map[string]interface{}{
+		"a": "",
+		"b": "true",
+		"c": "123",
+		"d": "- item",
+		"e": "key: value",
+		"f": "two\nlines",
+		"g": " padded",
+		"h": "plain text",
+		"i": nil,
+		"j": "no",
+		"k": "#comment",
+		"l": "2001-12-14",
+		"m": "1:2",
+		"n": "<<",
+		"o": "=",
+		"p": "1_000",
+		"q": "+.5",
+		"r": "v1.2",
+	}
+
+	res := httptest.NewRecorder()
+	err := render.YAML(res, http.StatusOK, v)
+
+	expectNil(t, err)
+	expect(t, res.Body.String(), `a: ""
+b: "true"
+c: "123"
+d: "- item"
+e: "key: value"
+f: "two\nlines"
+g: " padded"
+h: plain text
+i: null
+j: "no"
+k: "#comment"
+l: "2001-12-14"
+m: "1:2"
+"n": "<<"
+o: "="
+p: "1_000"
+q: "+.5"
+r: v1.2
+`)
+}
+
+func TestYAMLScalar(t *testing.T) {
+	render := New()
+
+	res := httptest.NewRecorder()
+	err := render.YAML(res, http.StatusOK, "hello")
+
+	expectNil(t, err)
+	expect(t, res.Body.String(), "hello\n")
+}
+
+func TestYAMLDisabledCharset(t *testing.T) {
+	render := New(Options{
+		DisableCharset:  true,
+		YAMLContentType: "application/x-yaml",
+	})
+
+	res := httptest.NewRecorder()
+	err := render.YAML(res, http.StatusOK, Greeting{"hello", "world"})
+
+	expectNil(t, err)
+	expect(t, res.Header().Get(ContentType), "application/x-yaml")
+}
+
+func TestYAMLWithError(t *testing.T) {
+	render := New()
+
+	res := httptest.NewRecorder()
+	err := render.YAML(res, http.StatusOK, math.NaN())
+
+	expectNotNil(t, err)
+	expect(t, res.Code, http.StatusInternalServerError)
+}
+
+func TestNegotiateYAML(t *testing.T) {
+	render := New()
+
+	var err error
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err = render.Negotiate(w, r, http.StatusOK, Greeting{"hello", "world"})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	req.Header.Set("Accept", "application/yaml, application/json;q=0.5")
+	h.ServeHTTP(res, req)
+
+	expectNil(t, err)
+	expect(t, res.Header().Get(ContentType), ContentYAML+"; charset=UTF-8")
+	expect(t, res.Body.String(), "one: hello\ntwo: world\n")
+}
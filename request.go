@@ -0,0 +1,199 @@
+package render
+
+import (
+	"html/template"
+	"io"
+	"net/http"
+	"time"
+)
+
+// RequestOptions is a struct for overriding some rendering Options for a
+// specific request-aware call.
+type RequestOptions struct {
+	// ETag overrides Options.ETag.
+	ETag ETagMode
+	// LastModified is sent as the Last-Modified header and compared to the
+	// If-Modified-Since header of the request. A Last-Modified header that is
+	// already set on the response is used if it is zero.
+	LastModified time.Time
+	// Headers and Cookies sent with the response, see Head.
+	Headers http.Header
+	Cookies []*http.Cookie
+}
+
+// HTMLRequest is like HTML, but renders for the given request. Templates can
+// reach it through the request func, along with the funcs and default data of
+// Options.RequestFuncs and Options.RequestData. Rendering stops when the
+// request's context is done.
+//
+// Like the other request-aware variants, the response is compressed if
+// Options.Compression is set, and conditional requests are answered with 304
+// Not Modified if an ETag or a modification time is available.
+func (r *Render) HTMLRequest(w io.Writer, req *http.Request, status int, name string, binding interface{}, htmlOpt ...HTMLOptions) error {
+	opt := r.prepareHTMLOptions(htmlOpt)
+	reqOpt := RequestOptions{
+		ETag:         opt.ETag,
+		LastModified: opt.LastModified,
+	}
+
+	return r.serve(w, req, opt.Streaming, opt.Streaming, reqOpt, func(w io.Writer) error {
+		return r.html(w, req, status, name, "", binding, htmlOpt)
+	})
+}
+
+// DataRequest is like Data, but does not render once the request's context is done.
+func (r *Render) DataRequest(w io.Writer, req *http.Request, status int, v []byte, reqOpt ...RequestOptions) error {
+	opt := prepareRequestOptions(reqOpt)
+	return r.serve(w, req, false, true, opt, func(w io.Writer) error {
+		return r.data(w, status, v, opt)
+	})
+}
+
+// JSONRequest is like JSON, but does not render once the request's context is done.
+func (r *Render) JSONRequest(w io.Writer, req *http.Request, status int, v interface{}, reqOpt ...RequestOptions) error {
+	opt := prepareRequestOptions(reqOpt)
+	return r.serve(w, req, r.opt.StreamingJSON, r.opt.StreamingJSON, opt, func(w io.Writer) error {
+		return r.json(w, status, v, opt)
+	})
+}
+
+// JSONPRequest is like JSONP, but does not render once the request's context is done.
+func (r *Render) JSONPRequest(w io.Writer, req *http.Request, status int, callback string, v interface{}, reqOpt ...RequestOptions) error {
+	opt := prepareRequestOptions(reqOpt)
+	return r.serve(w, req, false, false, opt, func(w io.Writer) error {
+		return r.jsonp(w, status, callback, v, opt)
+	})
+}
+
+// XMLRequest is like XML, but does not render once the request's context is done.
+func (r *Render) XMLRequest(w io.Writer, req *http.Request, status int, v interface{}, reqOpt ...RequestOptions) error {
+	opt := prepareRequestOptions(reqOpt)
+	return r.serve(w, req, false, false, opt, func(w io.Writer) error {
+		return r.xml(w, status, v, opt)
+	})
+}
+
+// TextRequest is like Text, but does not render once the request's context is done.
+func (r *Render) TextRequest(w io.Writer, req *http.Request, status int, v string, reqOpt ...RequestOptions) error {
+	opt := prepareRequestOptions(reqOpt)
+	return r.serve(w, req, false, false, opt, func(w io.Writer) error {
+		return r.text(w, status, v, opt)
+	})
+}
+
+// YAMLRequest is like YAML, but does not render once the request's context is done.
+func (r *Render) YAMLRequest(w io.Writer, req *http.Request, status int, v interface{}, reqOpt ...RequestOptions) error {
+	opt := prepareRequestOptions(reqOpt)
+	return r.serve(w, req, false, false, opt, func(w io.Writer) error {
+		return r.yaml(w, status, v, opt)
+	})
+}
+
+// MsgPackRequest is like MsgPack, but does not render once the request's context is done.
+func (r *Render) MsgPackRequest(w io.Writer, req *http.Request, status int, v interface{}, reqOpt ...RequestOptions) error {
+	opt := prepareRequestOptions(reqOpt)
+	return r.serve(w, req, false, false, opt, func(w io.Writer) error {
+		return r.msgPack(w, status, v, opt)
+	})
+}
+
+// CBORRequest is like CBOR, but does not render once the request's context is done.
+func (r *Render) CBORRequest(w io.Writer, req *http.Request, status int, v interface{}, reqOpt ...RequestOptions) error {
+	opt := prepareRequestOptions(reqOpt)
+	return r.serve(w, req, false, false, opt, func(w io.Writer) error {
+		return r.cbor(w, status, v, opt)
+	})
+}
+
+func prepareRequestOptions(reqOpt []RequestOptions) RequestOptions {
+	if len(reqOpt) > 0 {
+		return reqOpt[0]
+	}
+	return RequestOptions{}
+}
+
+// serve renders the response to a request through the conditional request
+// handling and the compression. Streamed responses are not buffered for the
+// conditional request handling, and compressed as they are written if
+// streamCompression is set.
+func (r *Render) serve(w io.Writer, req *http.Request, stream, streamCompression bool, opt RequestOptions, render func(w io.Writer) error) error {
+	if err := req.Context().Err(); err != nil {
+		return err
+	}
+
+	return r.withCompression(w, req, streamCompression, func(w io.Writer) error {
+		cw, done := r.conditional(w, req, stream, opt)
+		err := render(cw)
+		if cerr := done(); err == nil {
+			err = cerr
+		}
+		return err
+	})
+}
+
+// prepareRequestState adds the request, its funcs and its default data to
+// the state of an HTML render.
+func (r *Render) prepareRequestState(state *renderState, req *http.Request) {
+	state.req = req
+
+	if r.opt.RequestFuncs != nil {
+		funcs := template.FuncMap{}
+		for k, v := range r.opt.RequestFuncs(req) {
+			funcs[k] = v
+		}
+		// Funcs of the call take precedence over those of the request.
+		for k, v := range state.funcs {
+			funcs[k] = v
+		}
+		state.funcs = funcs
+	}
+
+	if r.opt.RequestData != nil {
+		state.data = r.opt.RequestData(req)
+		state.binding = mergeRequestData(state.data, state.binding)
+	}
+}
+
+// mergeRequestData merges the default data of a request into nil and
+// map[string]interface{} bindings. Values of the binding take precedence.
+func mergeRequestData(data map[string]interface{}, binding interface{}) interface{} {
+	if len(data) == 0 {
+		return binding
+	}
+
+	var values map[string]interface{}
+	switch b := binding.(type) {
+	case nil:
+	case map[string]interface{}:
+		values = b
+	default:
+		return binding
+	}
+
+	merged := make(map[string]interface{}, len(data)+len(values))
+	for k, v := range data {
+		merged[k] = v
+	}
+	for k, v := range values {
+		merged[k] = v
+	}
+	return merged
+}
+
+// request returns the request rendered for, or nil.
+func (c *templateClone) request() (*http.Request, error) {
+	return c.state.req, nil
+}
+
+// requestData returns a value of the request's default data.
+func (c *templateClone) requestData(key string) (interface{}, error) {
+	return c.state.data[key], nil
+}
+
+// contextErr returns the error of the request's context once it is done.
+func (c *templateClone) contextErr() error {
+	if c.state.req == nil {
+		return nil
+	}
+	return c.state.req.Context().Err()
+}
@@ -0,0 +1,341 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"html/template"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ContentEventStream header value for server-sent events.
+	ContentEventStream = "text/event-stream"
+
+	// defaultSSEKeepAlive is the default interval of keep-alive comments.
+	defaultSSEKeepAlive = 15 * time.Second
+)
+
+// Event is a single server-sent event.
+type Event struct {
+	// ID is sent back by the client in the Last-Event-ID header when it
+	// reconnects.
+	ID string
+	// Event is the event type. The client treats events without one as
+	// "message" events.
+	Event string
+	// Data of the event. Strings and byte slices are sent as they are, other
+	// values are marshalled to JSON or rendered with SSEOptions.Partial.
+	Data interface{}
+	// Retry tells the client how long to wait before reconnecting.
+	Retry time.Duration
+}
+
+// ResumeIterator is an Iterator that resumes a stream after the event with
+// the given ID, taken from the Last-Event-ID header of the request. The ID is
+// empty on the first connection.
+type ResumeIterator func(lastEventID string, yield func(v interface{}) error) error
+
+// SSEOptions is a struct for overriding some rendering Options for a specific
+// SSE call.
+type SSEOptions struct {
+	// Partial is the name of a template that renders the data of the events
+	// instead of JSON.
+	Partial string
+	// KeepAlive overrides Options.SSEKeepAlive.
+	KeepAlive time.Duration
+	// Headers and Cookies sent with the response, see Head. Headers replace
+	// the default Cache-Control and X-Accel-Buffering headers.
+	Headers http.Header
+	Cookies []*http.Cookie
+}
+
+// SSE built-in renderer. It renders a channel, an Iterator, a ResumeIterator
+// or a slice of Events as server-sent events, and flushes every event. Values
+// that are not Events are sent as the data of an event.
+type SSE struct {
+	Head
+	// Context stops the stream when it is done. Defaults to context.Background().
+	Context context.Context
+	// LastEventID the client reconnected with. A slice of events is resumed
+	// after the event with this ID, and a ResumeIterator is passed the ID.
+	LastEventID string
+	// KeepAlive is the interval at which comments are sent while there are no
+	// events, so that proxies keep the connection open. Zero disables them.
+	KeepAlive time.Duration
+	// Indent and UnEscapeHTML apply to the JSON data of events.
+	Indent       bool
+	UnEscapeHTML bool
+	// Templates and Partial render the data of events if Partial is set.
+	Templates *template.Template
+	Partial   string
+}
+
+// Render a SSE response.
+func (s SSE) Render(w io.Writer, v interface{}) error {
+	if it, ok := v.(ResumeIterator); ok {
+		v = Iterator(func(yield func(v interface{}) error) error {
+			return it(s.LastEventID, yield)
+		})
+	}
+	values, err := newValueStream(v)
+	if err != nil {
+		return err
+	}
+	s.resume(values)
+
+	ctx := s.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	sw := &sseWriter{w: w}
+	sw.flusher, _ = w.(http.Flusher)
+	if hw, ok := w.(http.ResponseWriter); ok {
+		s.Head.Write(hw)
+	}
+	sw.flush()
+
+	if s.KeepAlive > 0 {
+		stop := sw.keepAlive(s.KeepAlive)
+		defer stop()
+	}
+
+	var buf bytes.Buffer
+	written := 0
+	err = values.each(ctx, func(v interface{}) error {
+		buf.Reset()
+		if err := s.writeEvent(&buf, v); err != nil {
+			return err
+		}
+		if err := sw.send(buf.Bytes()); err != nil {
+			return err
+		}
+		written++
+		return nil
+	})
+	if err != nil {
+		return &StreamError{Err: err, Written: written}
+	}
+	return nil
+}
+
+// resume skips the events of a slice up to the one with the last event ID.
+func (s SSE) resume(values *valueStream) {
+	if s.LastEventID == "" || values.v.Kind() != reflect.Slice {
+		return
+	}
+
+	for i := values.v.Len() - 1; i >= 0; i-- {
+		if e, ok := toEvent(values.v.Index(i).Interface()); ok && e.ID == s.LastEventID {
+			values.v = values.v.Slice(i+1, values.v.Len())
+			return
+		}
+	}
+}
+
+// writeEvent encodes an event in the text/event-stream format.
+func (s SSE) writeEvent(buf *bytes.Buffer, v interface{}) error {
+	e, ok := toEvent(v)
+	if !ok {
+		e = Event{Data: v}
+	}
+
+	data, err := s.eventData(e.Data)
+	if err != nil {
+		return err
+	}
+
+	if len(e.ID) > 0 {
+		buf.WriteString("id: " + sseField(e.ID) + "\n")
+	}
+	if len(e.Event) > 0 {
+		buf.WriteString("event: " + sseField(e.Event) + "\n")
+	}
+	if e.Retry > 0 {
+		buf.WriteString("retry: " + strconv.FormatInt(e.Retry.Milliseconds(), 10) + "\n")
+	}
+	if data != nil {
+		lines := strings.Split(strings.Replace(string(data), "\r\n", "\n", -1), "\n")
+		for _, line := range lines {
+			buf.WriteString("data: " + line + "\n")
+		}
+	}
+	buf.WriteByte('\n')
+	return nil
+}
+
+// eventData returns the data of an event, or nil if it has none.
+func (s SSE) eventData(v interface{}) ([]byte, error) {
+	switch d := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []byte(d), nil
+	case []byte:
+		return d, nil
+	}
+
+	var buf bytes.Buffer
+	if len(s.Partial) > 0 {
+		err := s.Templates.ExecuteTemplate(&buf, s.Partial, v)
+		return bytes.TrimRight(buf.Bytes(), "\n"), err
+	}
+
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(!s.UnEscapeHTML)
+	if s.Indent {
+		enc.SetIndent("", "  ")
+	}
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+func toEvent(v interface{}) (Event, bool) {
+	switch e := v.(type) {
+	case Event:
+		return e, true
+	case *Event:
+		if e != nil {
+			return *e, true
+		}
+	}
+	return Event{}, false
+}
+
+// sseField removes line breaks from a single line field.
+func sseField(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+// sseWriter serializes the writes of events and keep-alive comments.
+type sseWriter struct {
+	mu       sync.Mutex
+	w        io.Writer
+	flusher  http.Flusher
+	lastSent time.Time
+}
+
+// send writes and flushes b.
+func (sw *sseWriter) send(b []byte) error {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	if _, err := sw.w.Write(b); err != nil {
+		return err
+	}
+	sw.lastSent = time.Now()
+	sw.flush()
+	return nil
+}
+
+func (sw *sseWriter) flush() {
+	if sw.flusher != nil {
+		sw.flusher.Flush()
+	}
+}
+
+// keepAlive sends a comment whenever nothing was sent for the given interval,
+// until the returned func is called.
+func (sw *sseWriter) keepAlive(interval time.Duration) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	sw.lastSent = time.Now()
+
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval / 2)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				sw.mu.Lock()
+				if time.Since(sw.lastSent) >= interval {
+					if _, err := io.WriteString(sw.w, ": keep-alive\n\n"); err == nil {
+						sw.lastSent = time.Now()
+						sw.flush()
+					}
+				}
+				sw.mu.Unlock()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// LastEventID returns the ID of the last event a reconnecting client received,
+// or "" if it is connecting for the first time.
+func LastEventID(req *http.Request) string {
+	return req.Header.Get("Last-Event-ID")
+}
+
+// SSE streams the values of a channel, an Iterator, a ResumeIterator or a
+// slice to the client as server-sent events, until they are exhausted or the
+// request's context is done. The stream is resumed from the Last-Event-ID
+// header of the request.
+func (r *Render) SSE(w io.Writer, req *http.Request, v interface{}, sseOpt ...SSEOptions) error {
+	if err := req.Context().Err(); err != nil {
+		return err
+	}
+
+	var opt SSEOptions
+	if len(sseOpt) > 0 {
+		opt = sseOpt[0]
+	}
+	keepAlive := r.opt.SSEKeepAlive
+	if opt.KeepAlive != 0 {
+		keepAlive = opt.KeepAlive
+	}
+	if keepAlive < 0 {
+		keepAlive = 0
+	}
+
+	head := Head{
+		ContentType: r.opt.EventStreamContentType + r.compiledCharset,
+		Status:      http.StatusOK,
+		Headers: http.Header{
+			"Cache-Control":     {"no-cache"},
+			"X-Accel-Buffering": {"no"},
+		},
+		Cookies: opt.Cookies,
+	}
+	for k, v := range opt.Headers {
+		head.Headers[k] = v
+	}
+
+	s := SSE{
+		Head:         head,
+		Context:      req.Context(),
+		LastEventID:  LastEventID(req),
+		KeepAlive:    keepAlive,
+		Indent:       r.opt.IndentJSON,
+		UnEscapeHTML: r.opt.UnEscapeHTML,
+		Partial:      opt.Partial,
+	}
+
+	if len(opt.Partial) > 0 {
+		c, err := r.templateSet().get()
+		if err != nil {
+			return r.failRender(w, s, v, err, http.StatusInternalServerError)
+		}
+		defer c.release()
+		s.Templates = c.tpl
+	}
+
+	return r.Render(w, s, v)
+}
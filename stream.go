@@ -0,0 +1,72 @@
+package render
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+)
+
+// htmlStream is the state of a streaming HTML render. It is shared between
+// the HTML engine, which writes to it, and the layout funcs, which flush it.
+type htmlStream struct {
+	w       *bufio.Writer
+	hw      *headWriter
+	flusher http.Flusher
+}
+
+// start prepares the stream for writing to w. The head is only written along
+// with the first bytes of the body, so errors that occur before anything was
+// sent can still be answered with a clean error response.
+func (s *htmlStream) start(w io.Writer, head Head) {
+	s.hw = &headWriter{w: w, head: head}
+	s.w = bufio.NewWriter(s.hw)
+	s.flusher, _ = w.(http.Flusher)
+}
+
+// flush sends everything written so far to the client. The head is written
+// first if nothing was sent yet, as flushing would send an implicit 200.
+func (s *htmlStream) flush() error {
+	if err := s.w.Flush(); err != nil {
+		return err
+	}
+	if s.flusher != nil {
+		s.hw.writeHead()
+		s.flusher.Flush()
+	}
+	return nil
+}
+
+// streamErr returns err as a *StreamError if the head was already written,
+// in which case no error response can be sent anymore.
+func (s *htmlStream) streamErr(err error) error {
+	if err == nil || !s.hw.wroteHeader {
+		return err
+	}
+	return &StreamError{Err: err, Written: s.hw.written}
+}
+
+// headWriter writes the head right before the first bytes of the body.
+type headWriter struct {
+	w           io.Writer
+	head        Head
+	wroteHeader bool
+	// written is the number of bytes of the body written.
+	written int
+}
+
+func (hw *headWriter) Write(b []byte) (int, error) {
+	hw.writeHead()
+	n, err := hw.w.Write(b)
+	hw.written += n
+	return n, err
+}
+
+// writeHead writes the head unless it was already written.
+func (hw *headWriter) writeHead() {
+	if !hw.wroteHeader {
+		hw.wroteHeader = true
+		if rw, ok := hw.w.(http.ResponseWriter); ok {
+			hw.head.Write(rw)
+		}
+	}
+}
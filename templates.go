@@ -0,0 +1,348 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"sync"
+)
+
+// templateSet is a compiled set of templates. It is never modified after
+// compilation: every render executes a pooled clone of the set, which binds
+// the layout funcs for that render only. This allows HTML to be rendered
+// concurrently without any locking.
+type templateSet struct {
+	opt *Options
+	// funcs the templates were compiled with.
+	funcs template.FuncMap
+
+	// master is never executed so that it can always be cloned.
+	master *template.Template
+	// lookup is the clone handed out by Render.TemplateLookup.
+	lookup *template.Template
+	clones sync.Pool
+	// files the templates were compiled from, by path.
+	files map[string]templateFile
+	// parents of the layouts that declare one, by name.
+	parents map[string]string
+	// components found below the components directory, by name.
+	components map[string]*componentSpec
+}
+
+// templateFile is a template file as it was when it was compiled.
+type templateFile struct {
+	name  string
+	stamp string
+}
+
+// templateClone is a clone of a templateSet used by a single render at a time.
+type templateClone struct {
+	set *templateSet
+	tpl *template.Template
+	// funcs bound to this clone.
+	funcs template.FuncMap
+
+	state renderState
+}
+
+// renderState is the state of the render a templateClone is used for.
+type renderState struct {
+	// name of the page template.
+	name    string
+	binding interface{}
+	// layout is set when the page is rendered within a layout.
+	layout bool
+	// fragment is set when only a fragment of the page is rendered. Its
+	// partials and sections are still resolved against the page.
+	fragment bool
+	// layouts the page is rendered within, from the outermost one inwards.
+	layouts []string
+	// depth is the index of the layout that is executing.
+	depth int
+	// funcs that override the compiled funcs for this render only.
+	funcs template.FuncMap
+	// stream is set when the page is streamed to the client.
+	stream *htmlStream
+	// deferred partials that are rendered concurrently to the page.
+	deferred *deferredPartials
+	// req is the request rendered for, if any.
+	req *http.Request
+	// data is the default data of the request.
+	data map[string]interface{}
+}
+
+func newTemplateSet(opt *Options, master *template.Template, files map[string]templateFile) (*templateSet, error) {
+	lookup, err := master.Clone()
+	if err != nil {
+		return nil, err
+	}
+
+	return &templateSet{
+		opt:        opt,
+		funcs:      templateFuncs(opt),
+		master:     master,
+		lookup:     lookup,
+		files:      files,
+		parents:    layoutParents(opt, master, files),
+		components: componentSpecs(opt, master, files),
+	}, nil
+}
+
+// overridableFuncs are the helper funcs that give way to funcs of the same
+// name in Options.Funcs. They were added after yield, partial and current,
+// which always take precedence, so templates written against funcs of the
+// same name keep working.
+var overridableFuncs = map[string]bool{
+	"deferred":    true,
+	"request":     true,
+	"requestData": true,
+	"layout":      true,
+	"section":     true,
+	"partialWith": true,
+	"dict":        true,
+	"component":   true,
+	"props":       true,
+	"slots":       true,
+}
+
+// templateFuncs returns the funcs the templates are compiled with.
+func templateFuncs(opt *Options) template.FuncMap {
+	funcs := template.FuncMap{}
+	for _, tmp := range opt.Funcs {
+		for k, v := range tmp {
+			funcs[k] = v
+		}
+	}
+	for k, v := range helperFuncs {
+		if _, ok := funcs[k]; !ok || !overridableFuncs[k] {
+			funcs[k] = v
+		}
+	}
+	return funcs
+}
+
+// builtinFunc reports whether templates calling the given helper func reach
+// the built-in one, and not a func of the same name in Options.Funcs.
+func builtinFunc(opt *Options, name string) bool {
+	if !overridableFuncs[name] {
+		return true
+	}
+	for _, funcs := range opt.Funcs {
+		if _, ok := funcs[name]; ok {
+			return false
+		}
+	}
+	return true
+}
+
+// get returns a clone of the set that is not in use by any other render.
+func (s *templateSet) get() (*templateClone, error) {
+	if c, ok := s.clones.Get().(*templateClone); ok {
+		return c, nil
+	}
+
+	tpl, err := s.master.Clone()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &templateClone{
+		set: s,
+		tpl: tpl,
+	}
+	c.funcs = template.FuncMap{
+		"yield":       c.yield,
+		"current":     c.current,
+		"block":       c.block,
+		"partial":     c.partial,
+		"partialWith": c.partialWith,
+		"component":   c.component,
+		"section":     c.section,
+		"deferred":    c.deferred,
+		"request":     c.request,
+		"requestData": c.requestData,
+	}
+	for name := range c.funcs {
+		if !builtinFunc(s.opt, name) {
+			delete(c.funcs, name)
+		}
+	}
+	tpl.Funcs(c.funcs)
+
+	return c, nil
+}
+
+// bind prepares the clone for a render.
+func (c *templateClone) bind(state renderState) {
+	c.state = state
+	if len(state.funcs) > 0 {
+		c.tpl.Funcs(state.funcs)
+	}
+}
+
+// release undoes the per-render state and returns the clone to its set.
+func (c *templateClone) release() {
+	// Deferred partials still use the clone until they are done.
+	if c.state.deferred != nil {
+		c.state.deferred.wait()
+	}
+
+	if len(c.state.funcs) > 0 {
+		restore := template.FuncMap{}
+		for k := range c.state.funcs {
+			if fn, ok := c.funcs[k]; ok {
+				restore[k] = fn
+			} else if fn, ok := c.set.funcs[k]; ok {
+				restore[k] = fn
+			}
+		}
+		c.tpl.Funcs(restore)
+	}
+
+	c.state = renderState{}
+	c.set.clones.Put(c)
+}
+
+func (c *templateClone) execute(name string, binding interface{}) (*bytes.Buffer, error) {
+	buf := new(bytes.Buffer)
+	return buf, c.tpl.ExecuteTemplate(buf, name, binding)
+}
+
+func (c *templateClone) yield() (template.HTML, error) {
+	if !c.state.layout {
+		return "", fmt.Errorf("yield called with no layout defined")
+	}
+
+	// Each layout yields to the next inner one, the innermost to the page.
+	depth := c.state.depth + 1
+	if depth > len(c.state.layouts) {
+		return "", fmt.Errorf("yield called outside of a layout")
+	}
+	name := c.state.name
+	if depth < len(c.state.layouts) {
+		name = c.state.layouts[depth]
+	}
+
+	// When streaming, send everything up to here before rendering the page.
+	if c.state.stream != nil && depth == 1 {
+		if err := c.state.stream.flush(); err != nil {
+			return "", err
+		}
+	}
+
+	if err := c.contextErr(); err != nil {
+		return "", err
+	}
+
+	c.state.depth = depth
+	buf, err := c.execute(name, c.state.binding)
+	c.state.depth = depth - 1
+	// Return safe HTML here since we are rendering our own template.
+	return template.HTML(buf.String()), err
+}
+
+func (c *templateClone) current() (string, error) {
+	if !c.state.layout {
+		return "", nil
+	}
+	return c.state.name, nil
+}
+
+func (c *templateClone) block(partialName string) (template.HTML, error) {
+	log.Print("Render's `block` implementation is now depericated. Use `partial` as a drop in replacement.")
+	return c.renderPartial(partialName, c.set.opt.RequireBlocks)
+}
+
+func (c *templateClone) partial(partialName string) (template.HTML, error) {
+	return c.renderPartial(partialName, c.set.opt.RequirePartials)
+}
+
+func (c *templateClone) renderPartial(partialName string, required bool) (template.HTML, error) {
+	if !c.state.layout && !c.state.fragment {
+		return "", fmt.Errorf("block called with no layout defined")
+	}
+	return c.executePartial(partialName, c.state.binding, required)
+}
+
+// partialWith renders a partial like partial does, but with the given data
+// instead of the page's binding. It can also be called from pages that are
+// rendered without a layout.
+func (c *templateClone) partialWith(partialName string, data interface{}) (template.HTML, error) {
+	return c.executePartial(partialName, data, c.set.opt.RequirePartials)
+}
+
+func (c *templateClone) executePartial(partialName string, data interface{}, required bool) (template.HTML, error) {
+	if err := c.contextErr(); err != nil {
+		return "", err
+	}
+
+	if fullPartialName, ok := c.lookupPartial(partialName, required); ok {
+		buf, err := c.execute(fullPartialName, data)
+		// Return safe HTML here since we are rendering our own template.
+		return template.HTML(buf.String()), err
+	}
+	return "", nil
+}
+
+// dict builds a map from pairs of keys and values, to pass several values to
+// partialWith.
+func dict(pairs ...interface{}) (map[string]interface{}, error) {
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("dict expects pairs of keys and values, got %d arguments", len(pairs))
+	}
+
+	m := make(map[string]interface{}, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict keys must be strings, got %T", pairs[i])
+		}
+		m[key] = pairs[i+1]
+	}
+	return m, nil
+}
+
+// section renders a named section of the page, or of the innermost layout
+// that fills it. Without one, the optional fallback is rendered, escaped
+// unless it is template.HTML.
+func (c *templateClone) section(name string, fallback ...interface{}) (template.HTML, error) {
+	if !c.state.layout && !c.state.fragment {
+		return "", fmt.Errorf("section called with no layout defined")
+	}
+
+	if err := c.contextErr(); err != nil {
+		return "", err
+	}
+
+	owner := c.state.name
+	for i := len(c.state.layouts); i >= 0; i-- {
+		if i < len(c.state.layouts) {
+			owner = c.state.layouts[i]
+		}
+		if fullName := sectionName(name, owner); c.tpl.Lookup(fullName) != nil {
+			buf, err := c.execute(fullName, c.state.binding)
+			// Return safe HTML here since we are rendering our own template.
+			return template.HTML(buf.String()), err
+		}
+	}
+
+	if len(fallback) == 0 {
+		return "", nil
+	}
+	if h, ok := fallback[0].(template.HTML); ok {
+		return h, nil
+	}
+	return template.HTML(template.HTMLEscapeString(fmt.Sprint(fallback[0]))), nil
+}
+
+// lookupPartial resolves the template name of a partial of the current page.
+// It reports false if the partial should be skipped because it is missing.
+func (c *templateClone) lookupPartial(partialName string, required bool) (string, bool) {
+	fullPartialName := fmt.Sprintf("%s-%s", partialName, c.state.name)
+	if c.tpl.Lookup(fullPartialName) == nil && c.set.opt.RenderPartialsWithoutPrefix {
+		fullPartialName = partialName
+	}
+	return fullPartialName, required || c.tpl.Lookup(fullPartialName) != nil
+}
@@ -0,0 +1,225 @@
+package render
+
+import (
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"log"
+	"sort"
+	"time"
+)
+
+// ReloadEvent describes a reload of changed templates. It is passed to
+// Options.OnReload.
+type ReloadEvent struct {
+	// Paths of the template files that were added, changed or removed.
+	Added   []string
+	Changed []string
+	Removed []string
+	// Err is set if the templates failed to compile. The previously compiled
+	// templates are kept in that case.
+	Err error
+}
+
+// Reload checks the templates for changes and recompiles the ones that
+// changed. Removing a template, or changing one that defines named templates
+// with {{ define }} or {{ block }}, recompiles all of them, so that templates
+// it no longer defines are dropped. The new templates are
+// swapped in atomically, so renders in progress are not affected. If any
+// template fails to compile, the previous templates are kept and the error is
+// returned. Options.OnReload is called whenever changes were found.
+//
+// Reload is called in the background when Options.WatchTemplates is set, but
+// may also be called directly.
+func (r *Render) Reload() error {
+	r.reloadLk.Lock()
+	defer r.reloadLk.Unlock()
+
+	source := r.templateSource()
+	scanned, err := r.scanTemplates(source)
+	if err != nil {
+		return err
+	}
+
+	// Only act on changes since the last scan, so a template that fails to
+	// compile is reported once and not on every poll.
+	if r.scanned != nil && sameFiles(r.scanned, scanned) {
+		return nil
+	}
+	r.scanned = scanned
+
+	set := r.templateSet()
+	event := diffFiles(set.files, scanned)
+	if len(event.Added)+len(event.Changed)+len(event.Removed) == 0 {
+		return nil
+	}
+
+	if len(event.Removed) > 0 || set.definesTemplates(event.Changed) {
+		event.Err = r.Recompile()
+	} else {
+		event.Err = r.recompileFiles(set, source, append(event.Added, event.Changed...), scanned)
+	}
+
+	if r.opt.OnReload != nil {
+		r.opt.OnReload(event)
+	}
+	return event.Err
+}
+
+// recompileFiles parses the given files into a copy of the set, leaving the
+// other templates as they are.
+func (r *Render) recompileFiles(set *templateSet, source templateSource, paths []string, scanned map[string]templateFile) error {
+	templates, err := set.master.Clone()
+	if err != nil {
+		return err
+	}
+
+	files := make(map[string]templateFile, len(set.files))
+	for path, file := range set.files {
+		files[path] = file
+	}
+
+	errs := &CompileError{}
+	for _, path := range paths {
+		buf, err := source.readFile(path)
+		if err != nil {
+			errs.add(path, err)
+			continue
+		}
+
+		file := scanned[path]
+		if err := r.parseTemplate(templates, file.name, buf); err != nil {
+			errs.add(path, err)
+			continue
+		}
+		files[path] = file
+	}
+	checkLayouts(&r.opt, templates, files, errs)
+	if r.opt.StrictComponents {
+		checkComponents(&r.opt, templates, files, errs)
+	}
+	if err := errs.orNil(); err != nil {
+		return err
+	}
+
+	return r.setTemplates(templates, files)
+}
+
+// definesTemplates reports whether any of the given files defined named
+// templates when the set was compiled. Parsing such a file again would keep
+// the ones it no longer defines.
+func (s *templateSet) definesTemplates(paths []string) bool {
+	names := map[string]bool{}
+	for _, path := range paths {
+		names[s.files[path].name] = true
+	}
+
+	for _, t := range s.master.Templates() {
+		if t.Tree != nil && names[t.Tree.ParseName] && !names[t.Name()] {
+			return true
+		}
+	}
+	return false
+}
+
+// scanTemplates lists the template files with their current stamps.
+func (r *Render) scanTemplates(source templateSource) (map[string]templateFile, error) {
+	files := map[string]templateFile{}
+	err := source.walk(func(path, rel string, info fs.FileInfo) error {
+		ext := r.templateExtension(rel)
+		if ext == "" {
+			return nil
+		}
+
+		var buf []byte
+		if info == nil {
+			var err error
+			if buf, err = source.readFile(path); err != nil {
+				return err
+			}
+		}
+
+		files[path] = templateFile{
+			name:  rel[0 : len(rel)-len(ext)],
+			stamp: fileStamp(info, buf),
+		}
+		return nil
+	})
+	return files, err
+}
+
+// watchTemplates polls for changed templates until Close is called.
+func (r *Render) watchTemplates() {
+	ticker := time.NewTicker(r.opt.WatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := r.Reload(); err != nil && r.opt.OnReload == nil {
+				log.Print(err)
+			}
+		case <-r.stopWatching:
+			return
+		}
+	}
+}
+
+// Close stops watching the templates for changes.
+func (r *Render) Close() error {
+	r.closeOnce.Do(func() {
+		if r.stopWatching != nil {
+			close(r.stopWatching)
+		}
+	})
+	return nil
+}
+
+// fileStamp identifies the state of a file by its modification time and
+// size, or by a hash of its content if it has no FileInfo.
+func fileStamp(info fs.FileInfo, buf []byte) string {
+	if info != nil {
+		return fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size())
+	}
+
+	h := fnv.New64a()
+	h.Write(buf)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func sameFiles(a, b map[string]templateFile) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for path, file := range a {
+		if b[path] != file {
+			return false
+		}
+	}
+	return true
+}
+
+// diffFiles lists the files that were added, changed or removed in next.
+func diffFiles(prev, next map[string]templateFile) ReloadEvent {
+	var event ReloadEvent
+	for path, file := range next {
+		old, ok := prev[path]
+		switch {
+		case !ok:
+			event.Added = append(event.Added, path)
+		case old.stamp != file.stamp:
+			event.Changed = append(event.Changed, path)
+		}
+	}
+	for path := range prev {
+		if _, ok := next[path]; !ok {
+			event.Removed = append(event.Removed, path)
+		}
+	}
+
+	sort.Strings(event.Added)
+	sort.Strings(event.Changed)
+	sort.Strings(event.Removed)
+	return event
+}
@@ -0,0 +1,196 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// valueNode is a value decoded from JSON, with the key order of objects kept.
+type valueNode struct {
+	// kind is one of '{', '[' or 0 for scalars.
+	kind   byte
+	keys   []string
+	values []*valueNode
+	scalar interface{}
+}
+
+// decodeValue marshals v to JSON and decodes it into a valueNode, so that
+// encoders for other formats honor json struct tags and json.Marshaler.
+func decodeValue(v interface{}) (*valueNode, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	return decodeNode(dec)
+}
+
+func decodeNode(dec *json.Decoder) (*valueNode, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		n := &valueNode{kind: '{'}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			n.keys = append(n.keys, key.(string))
+			n.values = append(n.values, value)
+		}
+		_, err := dec.Token()
+		return n, err
+	case json.Delim('['):
+		n := &valueNode{kind: '['}
+		for dec.More() {
+			value, err := decodeNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			n.values = append(n.values, value)
+		}
+		_, err := dec.Token()
+		return n, err
+	}
+	return &valueNode{scalar: tok}, nil
+}
+
+// encodeYAML writes v as a YAML document, indenting nested blocks by indent
+// spaces.
+func encodeYAML(w io.Writer, v interface{}, indent int) error {
+	n, err := decodeValue(v)
+	if err != nil {
+		return err
+	}
+
+	e := &yamlEncoder{indent: indent}
+	if n.kind == 0 || len(n.values) == 0 {
+		e.buf.WriteString(e.inline(n))
+		e.buf.WriteByte('\n')
+	} else {
+		e.block(n, 0)
+	}
+
+	_, err = e.buf.WriteTo(w)
+	return err
+}
+
+type yamlEncoder struct {
+	buf    bytes.Buffer
+	indent int
+}
+
+// block writes a non-empty object or array, starting on the current line.
+// Its first line is expected to be indented already.
+func (e *yamlEncoder) block(n *valueNode, depth int) {
+	for i, value := range n.values {
+		if i > 0 {
+			e.buf.WriteString(strings.Repeat(" ", depth))
+		}
+
+		if n.kind == '{' {
+			e.buf.WriteString(yamlString(n.keys[i]))
+			e.buf.WriteByte(':')
+		} else {
+			e.buf.WriteByte('-')
+		}
+
+		switch {
+		case value.kind == 0 || len(value.values) == 0:
+			e.buf.WriteByte(' ')
+			e.buf.WriteString(e.inline(value))
+			e.buf.WriteByte('\n')
+		case n.kind == '[':
+			// Items of a sequence start right after the dash.
+			pad := e.indent
+			if pad < 2 {
+				pad = 2
+			}
+			e.buf.WriteString(strings.Repeat(" ", pad-1))
+			e.block(value, depth+pad)
+		default:
+			e.buf.WriteByte('\n')
+			e.buf.WriteString(strings.Repeat(" ", depth+e.indent))
+			e.block(value, depth+e.indent)
+		}
+	}
+}
+
+// inline returns a scalar, or an empty object or array, in flow style.
+func (e *yamlEncoder) inline(n *valueNode) string {
+	switch n.kind {
+	case '{':
+		return "{}"
+	case '[':
+		return "[]"
+	}
+
+	switch s := n.scalar.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(s)
+	case json.Number:
+		return s.String()
+	case string:
+		return yamlString(s)
+	}
+	return fmt.Sprint(n.scalar)
+}
+
+// yamlString returns s as a plain scalar, or double quoted if it would be
+// read back as something else.
+func yamlString(s string) string {
+	if yamlNeedsQuotes(s) {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+func yamlNeedsQuotes(s string) bool {
+	if s == "" || s != strings.TrimSpace(s) {
+		return true
+	}
+
+	switch strings.ToLower(s) {
+	case "~", "null", "true", "false", "yes", "no", "on", "off", "y", "n", ".nan", ".inf", "-.inf", "+.inf", "<<", "=":
+		return true
+	}
+	// Anything starting like a number may be read as one, a timestamp such as
+	// 2001-12-14 or a sexagesimal value such as 1:2.
+	if yamlStartsNumeric(s) {
+		return true
+	}
+
+	if strings.ContainsRune("-?:,[]{}#&*!|>'\"%@`", rune(s[0])) {
+		return true
+	}
+	if strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") {
+		return true
+	}
+	for _, r := range s {
+		if r < ' ' || r == 0x7f || r == '\u2028' || r == '\u2029' || r == '\ufeff' {
+			return true
+		}
+	}
+	return false
+}
+
+func yamlStartsNumeric(s string) bool {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "+"), ".")
+	return len(s) > 0 && s[0] >= '0' && s[0] <= '9'
+}